@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// List of positions indicating the presence of the next bitmap.
+const (
+	Secondary field.ID = 1
+	Tertiary  field.ID = 65
+)
+
+// Bitmap indicates the data elements present in a message, up to a tertiary bitmap.
+// The bits of the positions 1 and 65 indicate the presence of the secondary and tertiary bitmaps:
+// they are set or cleared with the data elements of these bitmaps.
+type Bitmap [3]uint64
+
+// Set sets the bit of the data element.
+// Positions out of the bitmaps or indicating the next bitmap are ignored.
+func (b *Bitmap) Set(id field.ID) {
+	if !isData(id) {
+		return
+	}
+	b[(id-1)/64] |= bit(id)
+	b.next()
+}
+
+// Clear clears the bit of the data element.
+// Positions out of the bitmaps or indicating the next bitmap are ignored.
+func (b *Bitmap) Clear(id field.ID) {
+	if !isData(id) {
+		return
+	}
+	b[(id-1)/64] &^= bit(id)
+	b.next()
+}
+
+// IsSet returns in success if the bit at this position is set.
+func (b Bitmap) IsSet(id field.ID) bool {
+	if id == 0 || int(id) > MaxField {
+		return false
+	}
+	return b[(id-1)/64]&bit(id) != 0
+}
+
+// Fields returns the positions of the data elements, in ascending order.
+func (b Bitmap) Fields() []field.ID {
+	var list []field.ID
+	for id := 2; id <= b.Len()*64; id++ {
+		if isData(field.ID(id)) && b.IsSet(field.ID(id)) {
+			list = append(list, field.ID(id))
+		}
+	}
+	return list
+}
+
+// Len returns the number of bitmaps: 1, 2 if a secondary bitmap is present, 3 with a tertiary one.
+func (b Bitmap) Len() int {
+	switch {
+	case !b.IsSet(Secondary):
+		return 1
+	case !b.IsSet(Tertiary):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// It returns 8 bytes by bitmap.
+func (b Bitmap) MarshalBinary() ([]byte, error) {
+	out := make([]byte, b.Len()*8)
+	for k := 0; k < b.Len(); k++ {
+		binary.BigEndian.PutUint64(out[k*8:], b[k])
+	}
+	return out, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (b *Bitmap) UnmarshalBinary(data []byte) error {
+	return b.unmarshal(data, b.decodeBinary)
+}
+
+// MarshalHex returns the bitmaps in upper case hexadecimal, 16 characters by bitmap.
+func (b Bitmap) MarshalHex() []byte {
+	out, _ := b.MarshalBinary()
+	return []byte(strings.ToUpper(hex.EncodeToString(out)))
+}
+
+// UnmarshalHex parses the bitmaps in hexadecimal.
+func (b *Bitmap) UnmarshalHex(data []byte) error {
+	return b.unmarshal(data, b.decodeHex)
+}
+
+// String implements the fmt.Stringer interface.
+// It returns the bits of the bitmaps, as characters '0' and '1'.
+func (b Bitmap) String() string {
+	return string(b.appendBits(make([]byte, 0, b.Len()*64)))
+}
+
+// appendBits appends the bits of the bitmaps as characters '0' and '1' to dst.
+func (b Bitmap) appendBits(dst []byte) []byte {
+	for id := 1; id <= b.Len()*64; id++ {
+		if b.IsSet(field.ID(id)) {
+			dst = append(dst, '1')
+		} else {
+			dst = append(dst, '0')
+		}
+	}
+	return dst
+}
+
+// decodeBinary reads the bitmaps written with 8 bytes each, and returns the number of bytes read.
+func (b *Bitmap) decodeBinary(src []byte) (int, error) {
+	return b.decode(src, 8, func(p []byte) (uint64, error) {
+		return binary.BigEndian.Uint64(p), nil
+	})
+}
+
+// decodeHex reads the bitmaps written in hexadecimal, and returns the number of bytes read.
+func (b *Bitmap) decodeHex(src []byte) (int, error) {
+	return b.decode(src, encoding.LenBitmap, func(p []byte) (uint64, error) {
+		return strconv.ParseUint(string(p), 16, 64)
+	})
+}
+
+func (b *Bitmap) decode(src []byte, size int, parse func(p []byte) (uint64, error)) (n int, err error) {
+	*b = Bitmap{}
+	for k := range b {
+		if len(src) < n+size {
+			return 0, errors.OutOfRange
+		}
+		b[k], err = parse(src[n : n+size])
+		if err != nil {
+			return 0, errors.Data
+		}
+		n += size
+		// The first bit indicates the presence of an other bitmap.
+		if b[k]>>63 == 0 {
+			return n, nil
+		}
+	}
+	// No more than three bitmaps.
+	return 0, errors.Data
+}
+
+func (b *Bitmap) unmarshal(data []byte, decode func(src []byte) (int, error)) error {
+	n, err := decode(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return errors.Length
+	}
+	return nil
+}
+
+// next sets the bits indicating the presence of the secondary and tertiary bitmaps.
+func (b *Bitmap) next() {
+	b[1] &^= bit(Tertiary)
+	if b[2] != 0 {
+		b[1] |= bit(Tertiary)
+	}
+	b[0] &^= bit(Secondary)
+	if b[1] != 0 {
+		b[0] |= bit(Secondary)
+	}
+}
+
+// bit returns the mask of the position in its bitmap.
+func bit(id field.ID) uint64 {
+	return 1 << uint(63-(id-1)%64)
+}
+
+// isData returns in success if the position is the one of a data element.
+func isData(id field.ID) bool {
+	return id > 0 && int(id) <= MaxField && id != Secondary && id != Tertiary
+}
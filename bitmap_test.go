@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestBitmap_Set(t *testing.T) {
+	var (
+		are = is.New(t)
+		b   iso8583.Bitmap
+	)
+	b.Set(3)
+	b.Set(11)
+	are.Equal(b.Len(), 1)
+	are.Equal(string(b.MarshalHex()), "2020000000000000")
+	// The positions of the bitmaps are not data elements.
+	b.Set(iso8583.Secondary)
+	b.Set(iso8583.Tertiary)
+	b.Set(0)
+	b.Set(193)
+	are.Equal(b.Len(), 1)
+	are.True(!b.IsSet(iso8583.Secondary))
+	// Secondary bitmap.
+	b.Set(70)
+	are.Equal(b.Len(), 2)
+	are.True(b.IsSet(iso8583.Secondary))
+	are.Equal(string(b.MarshalHex()), "A0200000000000000400000000000000")
+	// Tertiary bitmap.
+	b.Set(192)
+	are.Equal(b.Len(), 3)
+	are.True(b.IsSet(iso8583.Tertiary))
+	are.Equal(b.Fields(), []field.ID{3, 11, 70, 192})
+	are.Equal(len(b.String()), 192)
+
+	b.Clear(192)
+	are.Equal(b.Len(), 2)
+	b.Clear(70)
+	are.Equal(b.Len(), 1)
+	b.Clear(3)
+	are.Equal(b.Fields(), []field.ID{11})
+	are.Equal(b.String(), strings.Repeat("0", 10)+"1"+strings.Repeat("0", 53))
+}
+
+func TestBitmap_UnmarshalHex(t *testing.T) {
+	for i, tt := range []struct {
+		in     string
+		fields []field.ID
+		err    error
+	}{
+		{in: "2020000000000000", fields: []field.ID{3, 11}},
+		{in: "a0200000000000000400000000000000", fields: []field.ID{3, 11, 70}},
+		{in: "A020000000000000" + "8000000000000000" + "4000000000000001", fields: []field.ID{3, 11, 130, 192}},
+		{in: "A020000000000000", err: errors.OutOfRange},
+		{in: "20200000000000000400000000000000", err: errors.Length},
+		{in: "2020G00000000000", err: errors.Data},
+		{in: strings.Repeat("8000000000000000", 3), err: errors.Data},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			var (
+				are = is.New(t)
+				b   iso8583.Bitmap
+			)
+			err := b.UnmarshalHex([]byte(tt.in))
+			are.Equal(err, tt.err)
+			if err != nil {
+				return
+			}
+			are.Equal(b.Fields(), tt.fields)
+			are.Equal(string(b.MarshalHex()), strings.ToUpper(tt.in))
+
+			bin, err := b.MarshalBinary()
+			are.NoErr(err)
+			are.Equal(len(bin), len(tt.in)/2)
+			var c iso8583.Bitmap
+			are.NoErr(c.UnmarshalBinary(bin))
+			are.Equal(c, b)
+		})
+	}
+}
+
+func TestMessage_Bitmap(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = newMessage("0800", map[field.ID]string{7: "0420090613", 11: "900001", 70: "001"})
+	)
+	b := m.Bitmap()
+	are.Equal(b.Fields(), []field.ID{7, 11, 70})
+	out, err := iso8583.Marshal(m)
+	are.NoErr(err)
+	are.Equal(string(out[4:36]), string(b.MarshalHex()))
+
+	// Data elements of the tertiary bitmap must be defined by the specification.
+	m.Spec = field.Spec{130: {Format: field.Numeric, Size: 2}}
+	f := m.Spec.New(130)
+	f.Value = []byte("42")
+	m.Data[130] = f
+	out, err = iso8583.Marshal(m)
+	are.NoErr(err)
+	dst := &iso8583.Message{Spec: m.Spec}
+	are.NoErr(iso8583.Unmarshal(out, dst))
+	are.Equal(dst.Bitmap(), m.Bitmap())
+	are.Equal(dst.Data[130].String(), "42")
+	are.Equal(dst.Data[1].String(), m.Bitmap().String())
+
+	_, err = iso8583.Marshal(&iso8583.Message{Spec: m.Spec, MTI: m.MTI, Data: iso8583.Fields{65: field.New(65)}})
+	are.Equal(err.Error(), errors.New(errors.Data, 65).Error())
+	// No more than three bitmaps.
+	err = iso8583.Unmarshal([]byte("0800"+strings.Repeat("8000000000000000", 3)), new(iso8583.Message))
+	are.Equal(err, errors.Data)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+// Builder builds a message, checking each value against the definition of its data element as it is set.
+// The errors are accumulated and returned by Build.
+type Builder struct {
+	m    *Message
+	cal  *field.Calendar
+	errs errors.List
+}
+
+// NewMessage returns a builder of a message of this type, defined by the specification.
+// The spec overrides the built-in specification of the version of the MTI, it may be nil.
+func NewMessage(spec field.Spec, mti *MTI) *Builder {
+	b := &Builder{
+		m:   &Message{MTI: mti, Spec: spec, Data: Fields{}},
+		cal: new(field.Calendar),
+	}
+	if mti == nil || !mti.Valid() {
+		b.errs = append(b.errs, errors.MTI)
+	}
+	return b
+}
+
+// Calendar sets the calendar used to compose the dates and times, see SetTime.
+// By default, the local transaction date and time are in UTC.
+func (b *Builder) Calendar(c *field.Calendar) *Builder {
+	b.cal = c
+	return b
+}
+
+// Set sets the data element.
+func (b *Builder) Set(f field.Field) *Builder {
+	return b.set(b.m.data(f))
+}
+
+// SetString sets the value of the data element. The value of a binary data is in hexadecimal.
+func (b *Builder) SetString(id field.ID, s string) *Builder {
+	d := b.m.newData(id)
+	if d.Format == field.Binary {
+		v, err := hex.DecodeString(s)
+		if err != nil {
+			return b.fail(id, errors.Data)
+		}
+		return b.bytes(d, v)
+	}
+	d.Value = []byte(s)
+	if d.Type != field.Fixed {
+		d.Size = len(d.Value)
+	}
+	return b.set(d)
+}
+
+// SetBytes sets the value of a binary data element.
+func (b *Builder) SetBytes(id field.ID, v []byte) *Builder {
+	return b.bytes(b.m.newData(id), v)
+}
+
+// SetInt sets the value of a numeric data element, padded with leading zeros if its length is fixed.
+func (b *Builder) SetInt(id field.ID, n int64) *Builder {
+	d := b.m.newData(id)
+	if n < 0 || d.Format&(field.Numeric|field.Amount) != field.Numeric {
+		return b.fail(id, errors.Data)
+	}
+	return b.SetString(id, pad(n, d.Element, 0))
+}
+
+// SetAmount sets an amount in minor units.
+// With the format x+n, the value is prefixed by C for a credit or D for a debit, when the amount is negative.
+func (b *Builder) SetAmount(id field.ID, amount int64) *Builder {
+	d := b.m.newData(id)
+	switch {
+	case d.Format&field.Amount != 0 && amount < 0:
+		return b.SetString(id, "D"+pad(-amount, d.Element, 1))
+	case d.Format&field.Amount != 0:
+		return b.SetString(id, "C"+pad(amount, d.Element, 1))
+	default:
+		return b.SetInt(id, amount)
+	}
+}
+
+// SetTime sets a date or a time, formatted as expected by the data element with the calendar.
+func (b *Builder) SetTime(id field.ID, t time.Time) *Builder {
+	d := b.m.newData(id)
+	if err := b.cal.Compose(d, t); err != nil {
+		return b.fail(id, err)
+	}
+	return b.set(d)
+}
+
+// SetTLV sets the BER-TLV data objects, like the EMV data of the field 55.
+// The value is written in hexadecimal, except for a binary data element.
+func (b *Builder) SetTLV(id field.ID, list []tlv.TLV) *Builder {
+	v, err := tlv.Encode(list)
+	if err != nil {
+		return b.fail(id, err)
+	}
+	d := b.m.newData(id)
+	if d.Format == field.Binary {
+		return b.bytes(d, v)
+	}
+	return b.SetString(id, strings.ToUpper(hex.EncodeToString(v)))
+}
+
+// Build returns the message, or the errors of the values set.
+func (b *Builder) Build() (*Message, error) {
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	return b.m, nil
+}
+
+func (b *Builder) bytes(d *field.Data, v []byte) *Builder {
+	if err := d.SetBytes(v); err != nil {
+		return b.fail(d.ID(), err)
+	}
+	return b.set(d)
+}
+
+func (b *Builder) set(d *field.Data) *Builder {
+	if !isData(d.ID()) {
+		return b.fail(d.ID(), errors.Data)
+	}
+	// The size of a variable data is its length: checks the maximum of the definition.
+	if e := b.m.Element(d.ID()); e.Type != field.Fixed && len(d.Value) > e.Size {
+		return b.fail(d.ID(), errors.Length)
+	}
+	if _, err := field.Marshal(d); err != nil {
+		return b.fail(d.ID(), err)
+	}
+	b.m.Data[d.ID()] = d
+	return b
+}
+
+func (b *Builder) fail(id field.ID, err error) *Builder {
+	b.errs = append(b.errs, errors.New(err, int(id)))
+	return b
+}
+
+// pad returns the number, padded with leading zeros to the fixed length of the element, less n characters.
+func pad(i int64, e field.Element, n int) string {
+	s := strconv.FormatInt(i, 10)
+	if e.Type == field.Fixed && len(s) < e.Size-n {
+		s = strings.Repeat("0", e.Size-n-len(s)) + s
+	}
+	return s
+}
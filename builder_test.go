@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+func TestNewMessage(t *testing.T) {
+	var (
+		are = is.New(t)
+		now = time.Date(2019, 4, 20, 9, 6, 13, 0, time.UTC)
+	)
+	m, err := iso8583.NewMessage(nil, iso8583.NewMTI(iso8583.V1987, iso8583.Financial)).
+		SetString(2, "4000000000000002").
+		SetInt(11, 42).
+		SetAmount(4, 2500).
+		SetAmount(28, -150).
+		SetTime(7, now).
+		SetString(41, "TERM0001").
+		SetTLV(55, []tlv.TLV{{Tag: "9F02", Value: []byte{0, 0, 0, 0, 0x25, 0}}}).
+		SetString(64, "0123456789abcdef").
+		Build()
+	are.NoErr(err)
+	are.Equal(m.Type(), "0200")
+	are.Equal(m.Data[2].String(), "4000000000000002")
+	are.Equal(m.Data[4].String(), "000000002500")
+	are.Equal(m.Data[7].String(), "0420090613")
+	are.Equal(m.Data[11].String(), "000042")
+	are.Equal(m.Data[28].String(), "D0000150")
+	are.Equal(m.Data[55].String(), "9F0206000000002500")
+	_, err = iso8583.Marshal(m)
+	are.NoErr(err)
+
+	// The built-in specification of the version is used by default.
+	m, err = iso8583.NewMessage(nil, iso8583.NewMTI(iso8583.V1993, iso8583.Financial)).SetString(39, "000").Build()
+	are.NoErr(err)
+	are.Equal(m.Data[39].String(), "000")
+}
+
+func TestBuilder_Build(t *testing.T) {
+	var (
+		are  = is.New(t)
+		spec = field.Spec{48: {Type: field.LLVar, Format: field.Numeric, Size: 4}}
+	)
+	_, err := iso8583.NewMessage(spec, iso8583.NewMTI(iso8583.V1987, iso8583.Authorization)).
+		SetString(41, "TERM01").
+		SetInt(11, -1).
+		SetAmount(4, 1e12).
+		SetString(48, "12345").
+		SetBytes(64, []byte{1, 2}).
+		SetString(52, "XYZ").
+		SetString(65, "1").
+		SetString(3, "000000").
+		Build()
+	are.True(err != nil)
+	list, ok := err.(errors.List)
+	are.True(ok)
+	are.Equal(len(list), 7)
+	are.Equal(list[0].Error(), "field #41: invalid length")
+	are.Equal(list[1].Error(), "field #11: invalid data")
+	are.Equal(list[2].Error(), "field #4: invalid length")
+	are.Equal(list[3].Error(), "field #48: invalid length")
+
+	_, err = iso8583.NewMessage(nil, nil).Build()
+	are.Equal(err.Error(), errors.MTI.Error())
+}
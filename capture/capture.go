@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package capture reads and writes capture files of ISO 8583 traffic.
+//
+// A capture file starts with the magic "ISO8583C" and the version of the format on 2 bytes.
+// Then each record is written in big endian with:
+// the time in nanoseconds since the Unix epoch on 8 bytes, the direction on 1 byte,
+// the length of the connection identifier on 2 bytes followed by the identifier,
+// and the length of the frame on 4 bytes followed by the frame, without its length prefix.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Version is the version of the format.
+const Version = 1
+
+// MaxFrame is the maximum length of a frame read in a capture file.
+const MaxFrame = 1 << 24
+
+// Magic starts any capture file.
+const Magic = "ISO8583C"
+
+// Direction is the direction of a frame, seen from the server.
+type Direction uint8
+
+// List of directions.
+const (
+	// Inbound is a frame received by the server, like a request.
+	Inbound Direction = iota
+	// Outbound is a frame sent by the server, like a response.
+	Outbound
+)
+
+// String implements the fmt.Stringer interface.
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "in"
+	case Outbound:
+		return "out"
+	default:
+		return ""
+	}
+}
+
+// Record is a frame of the traffic.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	// Conn identifies the connection, like "10.0.0.1:5300".
+	Conn  string
+	Frame []byte
+}
+
+// Writer writes a capture file.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter writes the header of the capture file and returns a writer of records.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(Magic); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, uint16(Version)); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// Write writes the record.
+func (w *Writer) Write(r *Record) error {
+	if r.Direction > Outbound || len(r.Conn) > 1<<16-1 || len(r.Frame) > MaxFrame {
+		return errors.Data
+	}
+	b := make([]byte, 0, 15+len(r.Conn)+len(r.Frame))
+	b = appendUint(b, uint64(r.Time.UnixNano()), 8)
+	b = append(b, byte(r.Direction))
+	b = appendUint(b, uint64(len(r.Conn)), 2)
+	b = append(b, r.Conn...)
+	b = appendUint(b, uint64(len(r.Frame)), 4)
+	b = append(b, r.Frame...)
+	_, err := w.w.Write(b)
+	return err
+}
+
+// Flush writes any buffered data.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads a capture file.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the header of the capture file and returns a reader of records.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	b := make([]byte, len(Magic)+2)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, errors.Data
+	}
+	if !bytes.Equal(b[:len(Magic)], []byte(Magic)) {
+		return nil, errors.Data
+	}
+	if binary.BigEndian.Uint16(b[len(Magic):]) != Version {
+		return nil, errors.NotImplemented
+	}
+	return &Reader{r: br}, nil
+}
+
+// Read returns the next record or io.EOF at the end of the file.
+func (r *Reader) Read() (*Record, error) {
+	b := make([]byte, 11)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, err
+	}
+	rec := &Record{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b))),
+		Direction: Direction(b[8]),
+	}
+	if rec.Direction > Outbound {
+		return nil, errors.Data
+	}
+	conn, err := r.next(int(binary.BigEndian.Uint16(b[9:])))
+	if err != nil {
+		return nil, err
+	}
+	rec.Conn = string(conn)
+	if b, err = r.next(4); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(b)
+	if n > MaxFrame {
+		return nil, errors.Length
+	}
+	if rec.Frame, err = r.next(int(n)); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadAll returns all the records.
+func (r *Reader) ReadAll() ([]*Record, error) {
+	var list []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rec)
+	}
+}
+
+// next returns the next n bytes.
+func (r *Reader) next(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+func appendUint(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
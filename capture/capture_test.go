@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package capture_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/capture"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+)
+
+const (
+	// Network management request with the fields 7, 11, 12, 13, 15 and 70.
+	request  = "0800823A0000000000000400000000000000042009061390000109061304200420001"
+	response = "0810823A0000000000000400000000000000042009061390000109061304200420001"
+)
+
+func records() []*capture.Record {
+	at := time.Date(2019, 4, 20, 9, 6, 13, 0, time.UTC)
+	return []*capture.Record{
+		{Time: at, Direction: capture.Inbound, Conn: "a", Frame: []byte(request)},
+		{Time: at.Add(time.Millisecond), Direction: capture.Inbound, Conn: "b", Frame: []byte(request)},
+		{Time: at.Add(2 * time.Millisecond), Direction: capture.Outbound, Conn: "a", Frame: []byte(response)},
+		{Time: at.Add(3 * time.Millisecond), Direction: capture.Outbound, Conn: "b", Frame: []byte(response)},
+		{Time: at.Add(4 * time.Millisecond), Direction: capture.Inbound, Conn: "a", Frame: []byte(request)},
+		{Time: at.Add(5 * time.Millisecond), Direction: capture.Outbound, Conn: "a", Frame: []byte(response)},
+	}
+}
+
+func TestReader_Read(t *testing.T) {
+	var (
+		are = is.New(t)
+		buf bytes.Buffer
+		src = records()
+	)
+	w, err := capture.NewWriter(&buf)
+	are.NoErr(err)
+	for _, r := range src {
+		are.NoErr(w.Write(r))
+	}
+	are.NoErr(w.Flush())
+	are.True(strings.HasPrefix(buf.String(), capture.Magic))
+	b := buf.Bytes()
+
+	r, err := capture.NewReader(bytes.NewReader(b))
+	are.NoErr(err)
+	out, err := r.ReadAll()
+	are.NoErr(err)
+	are.Equal(len(out), len(src))
+	for k, v := range out {
+		are.True(v.Time.Equal(src[k].Time))
+		are.Equal(v.Direction, src[k].Direction)
+		are.Equal(v.Conn, src[k].Conn)
+		are.Equal(v.Frame, src[k].Frame)
+	}
+
+	// Truncated.
+	r, err = capture.NewReader(bytes.NewReader(b[:len(b)-1]))
+	are.NoErr(err)
+	_, err = r.ReadAll()
+	are.Equal(err, io.ErrUnexpectedEOF)
+	// Not a capture file.
+	_, err = capture.NewReader(strings.NewReader("0800"))
+	are.Equal(err, errors.Data)
+	_, err = capture.NewReader(strings.NewReader(capture.Magic + "\x00\x02"))
+	are.Equal(err, errors.NotImplemented)
+}
+
+// serve answers the requests: the STAN is incremented, the field 70 is changed on the second request.
+func serve(t *testing.T, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer func() { _ = conn.Close() }()
+			for n := 0; ; n++ {
+				b, err := frame.Binary2.Read(conn)
+				if err != nil {
+					return
+				}
+				s := "0810" + strings.Replace(string(b[4:]), "900001", "900002", 1)
+				if n == 1 {
+					s = s[:len(s)-3] + "301"
+				}
+				if err = frame.Binary2.Write(conn, []byte(s)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(conn)
+	}
+}
+
+func TestReplayer_Replay(t *testing.T) {
+	are := is.New(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	defer func() { _ = l.Close() }()
+	go serve(t, l)
+
+	r := &capture.Replayer{
+		Dial:    func() (net.Conn, error) { return net.Dial("tcp", l.Addr().String()) },
+		Prefix:  frame.Binary2,
+		Ignore:  capture.Volatile,
+		Timeout: time.Second,
+	}
+	out := r.Replay(records())
+	are.Equal(len(out), 3)
+	are.Equal(out[0].Request.Conn, "a")
+	are.True(out[0].OK())
+	are.Equal(out[1].Request.Conn, "a")
+	are.Equal(out[1].Differences.String(), "~ 70: \"001\" -> \"301\"\n")
+	are.Equal(out[2].Request.Conn, "b")
+	are.True(out[2].OK())
+
+	// Without ignored fields.
+	r.Ignore = nil
+	out = r.Replay(records()[:3])
+	are.Equal(out[0].Differences.String(), "~ 11: \"900001\" -> \"900002\"\n")
+
+	// Pipelined requests, answered in the reverse order.
+	pipelined := strings.Replace(request, "900001", "900003", 1)
+	r.Ignore = capture.Volatile
+	out = r.Replay([]*capture.Record{
+		{Direction: capture.Inbound, Conn: "p", Frame: []byte(request)},
+		{Direction: capture.Inbound, Conn: "p", Frame: []byte(pipelined)},
+		{Direction: capture.Outbound, Conn: "p", Frame: []byte("0810" + pipelined[4:len(pipelined)-3] + "301")},
+		{Direction: capture.Outbound, Conn: "p", Frame: []byte(response)},
+	})
+	are.Equal(len(out), 2)
+	are.True(out[0].OK())
+	are.True(out[1].OK())
+
+	// Unexpected response.
+	r.Ignore = capture.Volatile
+	out = r.Replay([]*capture.Record{{Direction: capture.Inbound, Conn: "u", Frame: []byte(request)}})
+	are.Equal(len(out), 1)
+	are.True(out[0].Response != nil)
+	are.Equal(out[0].Differences.String(), "~ mti: \"\" -> \"0810\"\n+ 15: \"0420\"\n+ 70: \"001\"\n")
+
+	// Unanswered request, as recorded.
+	r.Dial = func() (net.Conn, error) {
+		c, s := net.Pipe()
+		go func() { _, _ = io.Copy(io.Discard, s) }()
+		return c, nil
+	}
+	r.Timeout = 10 * time.Millisecond
+	out = r.Replay([]*capture.Record{{Direction: capture.Inbound, Conn: "u", Frame: []byte(request)}})
+	are.Equal(len(out), 1)
+	are.True(out[0].OK())
+	are.Equal(out[0].Response, nil)
+
+	// Server down.
+	r.Dial = func() (net.Conn, error) { return nil, errors.NotImplemented }
+	out = r.Replay(records())
+	are.Equal(len(out), 3)
+	are.Equal(out[2].Err, errors.NotImplemented)
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package capture
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/frame"
+)
+
+// Volatile lists the fields changing on each transmission:
+// the transmission date and time, the STAN, the local time and date and the retrieval reference number.
+var Volatile = []field.ID{7, 11, 12, 13, 37}
+
+// Replayer resends the recorded requests to a server and compares its responses with the recorded ones.
+// Each recorded connection is replayed on its own connection, one request at a time.
+type Replayer struct {
+	// Dial opens a connection to the server.
+	Dial func() (net.Conn, error)
+	// Prefix is the length prefix of the frames.
+	Prefix frame.Prefix
+	// New returns an empty message, with its format and specification, to unmarshal a frame.
+	// By default, the messages are in ASCII without header.
+	New func() *iso8583.Message
+	// Ignore lists the fields not compared, like the Volatile ones.
+	Ignore []field.ID
+	// Timeout is the maximum duration to wait for a response. Zero means no timeout.
+	// A request without recorded response waits for the timeout, so any response it gets is reported:
+	// without timeout, no response is read for such a request.
+	Timeout time.Duration
+}
+
+// Result is the comparison of a response with the recorded one.
+type Result struct {
+	Request *Record
+	// Expected is the recorded response, if any.
+	Expected *Record
+	// Response is the frame received from the server.
+	Response    []byte
+	Differences iso8583.Differences
+	Err         error
+}
+
+// OK returns true if the response matches the recorded one.
+func (r Result) OK() bool {
+	return r.Err == nil && len(r.Differences) == 0
+}
+
+// Replay resends the inbound records, and compares the responses with the recorded ones, field by field.
+// The recorded response of a request is the next outbound record of the same connection with
+// the same STAN (field 11), or the same retrieval reference number (field 37) without STAN,
+// so the pipelined requests are paired with their responses.
+// The results are sorted by connection, then in the order of the requests.
+func (r *Replayer) Replay(records []*Record) []Result {
+	var (
+		conns  []string
+		byConn = make(map[string][]*Record)
+	)
+	for _, rec := range records {
+		if _, ok := byConn[rec.Conn]; !ok {
+			conns = append(conns, rec.Conn)
+		}
+		byConn[rec.Conn] = append(byConn[rec.Conn], rec)
+	}
+	var (
+		wg  sync.WaitGroup
+		res = make([][]Result, len(conns))
+	)
+	for k, c := range conns {
+		wg.Add(1)
+		go func(k int, list []*Record) {
+			defer wg.Done()
+			res[k] = r.replay(list)
+		}(k, byConn[c])
+	}
+	wg.Wait()
+
+	var out []Result
+	for _, v := range res {
+		out = append(out, v...)
+	}
+	return out
+}
+
+// replay replays the records of one connection.
+func (r *Replayer) replay(records []*Record) []Result {
+	out := r.pair(records)
+	if len(out) == 0 {
+		return nil
+	}
+	conn, err := r.Dial()
+	if err != nil {
+		return fail(out, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	for k := range out {
+		if err = r.exchange(conn, &out[k]); err != nil {
+			// The connection is no longer usable.
+			return append(out[:k], fail(out[k:], err)...)
+		}
+		r.compare(&out[k])
+	}
+	return out
+}
+
+// pair returns the results of the inbound records, each one with its recorded response, if any.
+// A request is paired with the next unpaired outbound record with the same key, see key.
+// The search stops at the next request with the same key: the request was not answered.
+func (r *Replayer) pair(records []*Record) []Result {
+	var (
+		out    []Result
+		keys   = make([]string, len(records))
+		paired = make([]bool, len(records))
+	)
+	for k, rec := range records {
+		keys[k] = r.key(rec)
+	}
+	for k, rec := range records {
+		if rec.Direction != Inbound {
+			continue
+		}
+		res := Result{Request: rec}
+		for n := k + 1; n < len(records); n++ {
+			if paired[n] || keys[n] != keys[k] {
+				continue
+			}
+			if records[n].Direction == Outbound {
+				paired[n] = true
+				res.Expected = records[n]
+			}
+			break
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// key returns the STAN (field 11) of the recorded message, or its retrieval reference number (field 37).
+// It is empty if the message has neither or can not be decoded: such records are paired by order.
+func (r *Replayer) key(rec *Record) string {
+	m := r.message()
+	if err := iso8583.Unmarshal(rec.Frame, m); err != nil {
+		return ""
+	}
+	for _, id := range []field.ID{11, 37} {
+		if f, ok := m.Data[id]; ok {
+			return strconv.Itoa(int(id)) + ":" + f.String()
+		}
+	}
+	return ""
+}
+
+// exchange sends the request and reads the response.
+// Without recorded response, the request is expected to time out.
+func (r *Replayer) exchange(conn net.Conn, res *Result) error {
+	if r.Timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(r.Timeout)); err != nil {
+			return err
+		}
+	}
+	if err := r.Prefix.Write(conn, res.Request.Frame); err != nil {
+		return err
+	}
+	if res.Expected == nil && r.Timeout <= 0 {
+		return nil
+	}
+	b, err := r.Prefix.Read(conn)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() && res.Expected == nil {
+			return nil
+		}
+		return err
+	}
+	res.Response = b
+	return nil
+}
+
+// compare sets the differences between the response and the expected one.
+// An unexpected response differs from an empty message.
+func (r *Replayer) compare(res *Result) {
+	if res.Expected == nil && res.Response == nil {
+		return
+	}
+	want, got := r.message(), r.message()
+	if res.Expected != nil {
+		if res.Err = iso8583.Unmarshal(res.Expected.Frame, want); res.Err != nil {
+			return
+		}
+	}
+	if res.Err = iso8583.Unmarshal(res.Response, got); res.Err != nil {
+		return
+	}
+	ignore := make(map[field.ID]bool, len(r.Ignore))
+	for _, id := range r.Ignore {
+		ignore[id] = true
+	}
+	for _, d := range iso8583.Diff(want, got) {
+		if d.Field == 0 || !ignore[d.Field] {
+			res.Differences = append(res.Differences, d)
+		}
+	}
+}
+
+func (r *Replayer) message() *iso8583.Message {
+	if r.New == nil {
+		return new(iso8583.Message)
+	}
+	return r.New()
+}
+
+func fail(list []Result, err error) []Result {
+	for k := range list {
+		list[k].Err = err
+	}
+	return list
+}
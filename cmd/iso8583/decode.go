@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/field"
+)
+
+func decode(args []string, stdin io.Reader, stdout io.Writer) error {
+	var (
+		o      = newOptions("decode", "input")
+		asJSON = o.flags.Bool("json", false, "prints the message in JSON")
+	)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	m, err := o.unmarshal(stdin)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(stdout).Encode(iso8583.Redacted{Message: m})
+	}
+	return printMessage(stdout, m)
+}
+
+// printMessage writes the MTI, the bitmap and the data elements of the message, masking the sensitive values.
+func printMessage(w io.Writer, m *iso8583.Message) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "MTI\t\t%s\n", m.Type())
+	if _, ok := m.Data[1]; ok {
+		fmt.Fprintf(tw, "Bitmap\t\t%s\n", m.Bitmap().MarshalHex())
+	}
+	for _, id := range ids(m) {
+		f := m.Data[id]
+		fmt.Fprintf(tw, "%03d\t%s\t%s\n", id, m.Element(id), value(f))
+	}
+	return tw.Flush()
+}
+
+// ids returns the sorted positions of the data elements, except the bitmap.
+func ids(m *iso8583.Message) []field.ID {
+	var list []field.ID
+	for id := range m.Data {
+		if id > 1 {
+			list = append(list, id)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i] < list[j]
+	})
+	return list
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+func diff(args []string, _ io.Reader, stdout io.Writer) error {
+	var (
+		o      = newOptions("diff", "input")
+		asJSON = o.flags.Bool("json", false, "prints the differences in JSON")
+	)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() != 2 {
+		return errors.Data
+	}
+	a, err := o.parse(o.flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("first message: %w", err)
+	}
+	b, err := o.parse(o.flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("second message: %w", err)
+	}
+	out := iso8583.Diff(a, b)
+	for k := range out {
+		mask(&out[k])
+	}
+	if *asJSON {
+		if out == nil {
+			out = iso8583.Differences{}
+		}
+		return json.NewEncoder(stdout).Encode(out)
+	}
+	_, err = io.WriteString(stdout, out.String())
+	return err
+}
+
+// mask masks the sensitive values of the difference.
+func mask(d *iso8583.Difference) {
+	if d.Field == 55 {
+		if p := strings.Split(d.Path, "."); tlv.Sensitive(p[len(p)-1]) {
+			d.Old = strings.Repeat(field.MaskChar, len(d.Old))
+			d.New = strings.Repeat(field.MaskChar, len(d.New))
+		}
+		return
+	}
+	d.Old = field.MaskValue(d.Field, d.Old)
+	d.New = field.MaskValue(d.Field, d.New)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+)
+
+func encode(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := newOptions("encode", "output")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	r := stdin
+	if o.flags.NArg() > 0 {
+		f, err := os.Open(o.flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m, err := o.message()
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, m); err != nil {
+		return err
+	}
+	// The flags take precedence over the description.
+	if o.isSet("format") {
+		m.Format, _ = encoding.Parse(o.format)
+	}
+	if o.isSet("header") {
+		m.Header = o.header
+	}
+	b, err = iso8583.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return o.write(stdout, b)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/loadtest"
+)
+
+func load(args []string, _ io.Reader, stdout io.Writer) error {
+	var (
+		o        = newOptions("load", "")
+		addr     = o.flags.String("addr", "", "address of the server, like localhost:5300")
+		framing  = o.flags.String("framing", frame.Binary2.String(), "length prefix of the frames: 2b, 4b, 4a or 2bcd")
+		conns    = o.flags.Int("conns", 1, "number of concurrent connections")
+		tps      = o.flags.Float64("tps", 10, "target number of requests by second, 0 for as fast as possible")
+		requests = o.flags.Int("requests", 0, "number of requests to send, 0 for no limit")
+		duration = o.flags.Duration("duration", 0, "duration of the test, 0 for no limit")
+		mix      = o.flags.String("mix", "0100:1", "comma separated weights of the types of request: 0100, 0200, 0400 or 0800")
+		timeout  = o.flags.Duration("timeout", 5*time.Second, "maximum duration to wait for a response")
+		signOn   = o.flags.Bool("sign-on", false, "sends a sign-on on each connection before the requests")
+		bin      = o.flags.String("bin", loadtest.DefaultBIN, "prefix of the generated PANs")
+		asJSON   = o.flags.Bool("json", false, "prints the report in JSON")
+	)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if *addr == "" || o.flags.NArg() != 0 {
+		return errors.Data
+	}
+	prefix, err := frame.ParsePrefix(*framing)
+	if err != nil {
+		return err
+	}
+	weights, err := s2mix(*mix)
+	if err != nil {
+		return err
+	}
+	if _, err = o.message(); err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	r := &loadtest.Runner{
+		Dial: func() (net.Conn, error) {
+			return net.DialTimeout("tcp", *addr, *timeout)
+		},
+		Prefix: prefix,
+		Generator: &loadtest.Generator{
+			BIN: *bin,
+			New: func() *iso8583.Message {
+				m, _ := o.message()
+				return m
+			},
+		},
+		Mix:      weights,
+		Conns:    *conns,
+		TPS:      *tps,
+		Requests: *requests,
+		Timeout:  *timeout,
+		SignOn:   *signOn,
+	}
+	rep, err := r.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(stdout).Encode(rep)
+	}
+	_, err = fmt.Fprint(stdout, rep)
+	return err
+}
+
+// s2mix parses the comma separated list of weights by type of request, like 0100:6,0400:1.
+func s2mix(s string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		p := strings.SplitN(v, ":", 2)
+		w := 1
+		if len(p) == 2 {
+			n, err := strconv.Atoi(p[1])
+			if err != nil || n < 0 {
+				return nil, errors.Data
+			}
+			w = n
+		}
+		out[p[0]] = w
+	}
+	return out, nil
+}
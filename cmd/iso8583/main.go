@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Command iso8583 decodes, encodes and validates ISO 8583 messages.
+//
+// Usage:
+//
+//	iso8583 decode [--format ascii|bcd|ebcdic] [--header] [--spec file] [--input hex|raw|base64] [message]
+//	iso8583 encode [--format ascii|bcd|ebcdic] [--header] [--spec file] [--output hex|raw|base64] [file.json]
+//	iso8583 validate [--format ascii|bcd|ebcdic] [--header] [--spec file] [--input hex|raw|base64] [message]
+//	iso8583 diff [--format ascii|bcd|ebcdic] [--header] [--spec file] [--input hex|raw|base64] [--json] message message
+//	iso8583 replay --addr host:port [--framing 2b|4b|4a|2bcd] [--ignore 7,11,12,13,37] [--timeout 5s] [--json] file
+//	iso8583 pcap [--framing 2b|4b|4a|2bcd] [--port 5300] [--json] [--capture file] file
+//	iso8583 simulate [--addr localhost:5300] scenario.yaml
+//	iso8583 load --addr host:port [--framing 2b|4b|4a|2bcd] [--conns 1] [--tps 10] [--requests 0] [--duration 0]
+//	             [--mix 0100:6,0200:3,0400:1] [--timeout 5s] [--sign-on] [--bin 400000] [--json]
+//
+// Without argument, the message is read on the standard input.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a sub-command of the tool.
+type command struct {
+	name, usage string
+	run         func(args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = []command{
+	{name: "diff", usage: "prints the differences between two messages", run: diff},
+	{name: "decode", usage: "prints the MTI, the bitmap and the fields of a message", run: decode},
+	{name: "encode", usage: "encodes a message described in JSON", run: encode},
+	{name: "validate", usage: "checks the MTI and the fields of a message", run: validate},
+	{name: "replay", usage: "resends the requests of a capture file and compares the responses", run: replay},
+	{name: "pcap", usage: "extracts the messages of the TCP streams of a pcap or pcapng file", run: extract},
+	{name: "load", usage: "sends generated requests at a target rate and reports the responses", run: load},
+	{name: "simulate", usage: "answers the requests like a host, as described by a YAML scenario", run: simulate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name != args[0] {
+				continue
+			}
+			if err := c.run(args[1:], stdin, stdout); err != nil {
+				fmt.Fprintf(stderr, "iso8583 %s: %s\n", c.name, err)
+				return 1
+			}
+			return 0
+		}
+	}
+	fmt.Fprintln(stderr, "usage: iso8583 <command> [flags] [message]")
+	for _, c := range commands {
+		fmt.Fprintf(stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+	return 2
+}
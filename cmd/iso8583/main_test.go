@@ -0,0 +1,211 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/capture"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/simulator"
+)
+
+const (
+	request = "0800823A0000000000000400000000000000042009061390000109061304200420001"
+	// Financial request with a PIN block and a primary account number.
+	financial = "0200" + "7000000000001000" + "134012345678909" + "000000" + "000000001000" + "1B9C1845EB993A7A"
+)
+
+func TestRun(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			args   []string
+			stdin  string
+			code   int
+			stdout []string
+			stderr string
+		}{
+			{code: 2, stderr: "usage: iso8583"},
+			{args: []string{"unknown"}, code: 2, stderr: "usage: iso8583"},
+			{
+				args:   []string{"decode", "--input", "raw", request},
+				stdout: []string{"MTI           0800", "Bitmap        823A0000000000000400000000000000", "070     n 3   001"},
+			},
+			{
+				args:   []string{"decode"},
+				stdin:  strings.ToUpper(hex.EncodeToString([]byte(financial))) + "\n",
+				stdout: []string{"002     n..19  401234***8909", "052     b 64   ****************"},
+			},
+			{
+				args:   []string{"decode", "--header", "--format", "ebcdic"},
+				stdin:  hex.EncodeToString(encoding.ASCIIToEBCDIC([]byte("0069" + request))),
+				stdout: []string{"011     n 6   900001"},
+			},
+			{args: []string{"decode", "--format", "utf8", request}, code: 1, stderr: "not implemented"},
+			{args: []string{"decode", "--input", "raw", "0800"}, code: 1, stderr: "out of range"},
+			{
+				args:   []string{"encode", "--output", "raw", "--format", "ascii", "../../testdata/ascii_network_management_request.json"},
+				stdout: []string{request},
+			},
+			{
+				args:   []string{"encode", "--output", "base64"},
+				stdin:  `{"mti": "0800", "fields": {"11": "000001"}}`,
+				stdout: []string{"MDgwMDAwMjAwMDAwMDAwMDAwMDAwMDAwMDE="},
+			},
+			{args: []string{"encode"}, stdin: `{"mti": "0800", "fields": {"11": "1A"}}`, code: 1, stderr: "field #11"},
+			{
+				args:   []string{"decode", "--json"},
+				stdin:  strings.ToUpper(hex.EncodeToString([]byte(financial))),
+				stdout: []string{`"2":"401234***8909"`, `"4":{"value":"000000001000","amount":1000}`, `"52":"****************"`},
+			},
+			{
+				args:   []string{"diff", "--input", "raw", request, "0810" + request[4:len(request)-3] + "301"},
+				stdout: []string{"~ mti: \"0800\" -> \"0810\"\n~ 70: \"001\" -> \"301\"\n"},
+			},
+			{
+				args: []string{
+					"diff", "--json",
+					strings.ToUpper(hex.EncodeToString([]byte(financial))),
+					strings.ToUpper(hex.EncodeToString([]byte(strings.Replace(financial, "134012345678909", "134012345678919", 1)))),
+				},
+				stdout: []string{`[{"change":"changed","field":2,"path":"2","old":"401234***8909","new":"401234***8919"}]`},
+			},
+			{args: []string{"diff", "--json", "--input", "raw", request, request}, stdout: []string{"[]"}},
+			{args: []string{"diff", request}, code: 1, stderr: "invalid data"},
+			{
+				args:   []string{"pcap", "--port", "5300", "../../testdata/network_management.pcap"},
+				stdout: []string{"2019-04-20T09:06:13.003Z 10.0.0.1:40000 -> 10.0.0.2:5300\nMTI           0800\n", "039     an 2  00\n"},
+			},
+			{
+				args:   []string{"pcap", "--json", "../../testdata/network_management.pcap"},
+				stdout: []string{`"src":"10.0.0.2:5300","dst":"10.0.0.1:40000","message":{"encoding":"ascii","mti":"0810"`},
+			},
+			{args: []string{"pcap", "--capture", "out.cap", "../../testdata/network_management.pcap"}, code: 1, stderr: "invalid data"},
+			{args: []string{"simulate"}, code: 1, stderr: "invalid data"},
+			{args: []string{"simulate", "../../testdata/network_management.pcap"}, code: 1, stderr: "yaml"},
+			{args: []string{"simulate", "--addr", "localhost:-1", "../../testdata/scenario.yaml"}, code: 1, stderr: "invalid port"},
+			{args: []string{"validate", "--input", "base64", "MDgwMDAwMjAwMDAwMDAwMDAwMDAwMDAwMDE="}, stdout: []string{"valid"}},
+			{
+				args:   []string{"validate", "--input", "raw", "08000020000000000000A0000B"},
+				code:   1,
+				stderr: "field #11: invalid data",
+			},
+			{args: []string{"validate", "--input", "raw", request + "99"}, code: 1, stderr: "invalid length"},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			are.Equal(run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr), tt.code)
+			for _, s := range tt.stdout {
+				are.True(strings.Contains(stdout.String(), s))
+			}
+			are.True(strings.Contains(stderr.String(), tt.stderr))
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	are := is.New(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	defer func() { _ = l.Close() }()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			b, err := frame.ASCII4.Read(conn)
+			if err != nil {
+				return
+			}
+			// Another STAN and response code.
+			s := "0810" + strings.Replace(string(b[4:]), "900001", "900002", 1)
+			if err = frame.ASCII4.Write(conn, []byte(s[:len(s)-3]+"301")); err != nil {
+				return
+			}
+		}
+	}()
+
+	name := filepath.Join(t.TempDir(), "traffic.cap")
+	f, err := os.Create(name)
+	are.NoErr(err)
+	w, err := capture.NewWriter(f)
+	are.NoErr(err)
+	are.NoErr(w.Write(&capture.Record{Direction: capture.Inbound, Conn: "a", Frame: []byte(request)}))
+	are.NoErr(w.Write(&capture.Record{Direction: capture.Outbound, Conn: "a", Frame: []byte("0810" + request[4:])}))
+	are.NoErr(w.Flush())
+	are.NoErr(f.Close())
+
+	// Extracted from a network capture.
+	var stdout, stderr bytes.Buffer
+	pcapName := filepath.Join(t.TempDir(), "pcap.cap")
+	are.Equal(run([]string{"pcap", "--port", "5300", "--capture", pcapName, "../../testdata/network_management.pcap"}, nil, &stdout, &stderr), 0)
+	f, err = os.Open(pcapName)
+	are.NoErr(err)
+	r, err := capture.NewReader(f)
+	are.NoErr(err)
+	records, err := r.ReadAll()
+	are.NoErr(err)
+	are.NoErr(f.Close())
+	are.Equal(len(records), 2)
+	are.Equal(records[0].Conn, "10.0.0.1:40000")
+	are.Equal(records[1].Conn, "10.0.0.1:40000")
+	are.Equal(records[1].Direction, capture.Outbound)
+
+	code := run([]string{"replay", "--addr", l.Addr().String(), "--framing", "4a", "--json", name}, nil, &stdout, &stderr)
+	are.Equal(code, 1)
+	are.True(strings.Contains(stdout.String(), `"differences":[{"change":"changed","field":70,"path":"70","old":"001","new":"301"}]`))
+	are.Equal(stderr.String(), "iso8583 replay: 1 of 1 response(s) differ\n")
+
+	stderr.Reset()
+	are.Equal(run([]string{"replay", name}, nil, &stdout, &stderr), 1)
+	are.Equal(stderr.String(), "iso8583 replay: invalid data\n")
+}
+
+func TestLoad(t *testing.T) {
+	are := is.New(t)
+	f, err := os.Open("../../testdata/scenario.yaml")
+	are.NoErr(err)
+	s, err := simulator.Load(f)
+	are.NoErr(err)
+	are.NoErr(f.Close())
+	srv := &simulator.Server{Scenario: s}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	go func() { _ = srv.Serve(l) }()
+	defer func() { _ = srv.Close() }()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"load", "--addr", l.Addr().String(), "--conns", "2", "--tps", "0", "--requests", "10",
+		"--mix", "0100:2,0200,0800", "--sign-on",
+	}, nil, &stdout, &stderr)
+	are.Equal(stderr.String(), "")
+	are.Equal(code, 0)
+	are.True(strings.Contains(stdout.String(), "requests: 10 in"))
+	are.True(strings.Contains(stdout.String(), "timeouts: 0 (0.0%)"))
+
+	stdout.Reset()
+	are.Equal(run([]string{"load", "--addr", l.Addr().String(), "--requests", "1", "--tps", "0", "--json"}, nil, &stdout, &stderr), 0)
+	are.True(strings.Contains(stdout.String(), `"codes":{"91":1}`))
+
+	are.Equal(run([]string{"load", "--addr", l.Addr().String(), "--mix", "0300"}, nil, &stdout, &stderr), 1)
+	are.Equal(run([]string{"load", "--addr", l.Addr().String(), "--mix", "0100:x"}, nil, &stdout, &stderr), 1)
+	are.Equal(run([]string{"load"}, nil, &stdout, &stderr), 1)
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// List of representations of the message on the command line.
+const (
+	hexData    = "hex"
+	rawData    = "raw"
+	base64Data = "base64"
+)
+
+// options mirrors the options of iso8583.Message.
+type options struct {
+	format string
+	header bool
+	spec   string
+	data   string
+	flags  *flag.FlagSet
+}
+
+// newOptions returns the flags of the command.
+// The data flag, if any, defines the representation of the message: input or output.
+func newOptions(name, data string) *options {
+	o := &options{flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	o.flags.SetOutput(ioutil.Discard)
+	o.flags.StringVar(&o.format, "format", "ascii", "encoding of the message: ascii, bcd or ebcdic")
+	o.flags.BoolVar(&o.header, "header", false, "the message starts with its length")
+	o.flags.StringVar(&o.spec, "spec", "", "JSON file overriding the definition of data elements")
+	if data != "" {
+		o.flags.StringVar(&o.data, data, hexData, "representation of the message: hex, raw or base64")
+	}
+	return o
+}
+
+// isSet returns true if the flag has been explicitly set.
+func (o *options) isSet(name string) bool {
+	var ok bool
+	o.flags.Visit(func(f *flag.Flag) {
+		ok = ok || f.Name == name
+	})
+	return ok
+}
+
+// message returns an empty message with the options.
+func (o *options) message() (*iso8583.Message, error) {
+	var (
+		m   = &iso8583.Message{Header: o.header}
+		err error
+	)
+	m.Format, err = encoding.Parse(o.format)
+	if err != nil {
+		return nil, err
+	}
+	if o.spec == "" {
+		return m, nil
+	}
+	b, err := ioutil.ReadFile(o.spec)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &m.Spec)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// read returns the data passed as argument or else on the input.
+func (o *options) read(stdin io.Reader) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if o.flags.NArg() > 0 {
+		b = []byte(strings.Join(o.flags.Args(), ""))
+	} else if b, err = ioutil.ReadAll(stdin); err != nil {
+		return nil, err
+	}
+	return o.decode(b)
+}
+
+// decode returns the data behind its representation.
+func (o *options) decode(b []byte) ([]byte, error) {
+	switch o.data {
+	case rawData:
+		return b, nil
+	case hexData:
+		return hex.DecodeString(string(bytes.Map(space, b)))
+	case base64Data:
+		return base64.StdEncoding.DecodeString(string(bytes.Map(space, b)))
+	default:
+		return nil, errors.NotImplemented
+	}
+}
+
+// unmarshal parses the message read on the input.
+func (o *options) unmarshal(stdin io.Reader) (*iso8583.Message, error) {
+	m, err := o.message()
+	if err != nil {
+		return nil, err
+	}
+	b, err := o.read(stdin)
+	if err != nil {
+		return nil, err
+	}
+	return m, iso8583.Unmarshal(b, m)
+}
+
+// parse parses the message given in argument.
+func (o *options) parse(s string) (*iso8583.Message, error) {
+	m, err := o.message()
+	if err != nil {
+		return nil, err
+	}
+	b, err := o.decode([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+	return m, iso8583.Unmarshal(b, m)
+}
+
+// write prints the data with the expected representation.
+func (o *options) write(w io.Writer, b []byte) error {
+	var s string
+	switch o.data {
+	case rawData:
+		_, err := w.Write(b)
+		return err
+	case hexData:
+		s = strings.ToUpper(hex.EncodeToString(b))
+	case base64Data:
+		s = base64.StdEncoding.EncodeToString(b)
+	default:
+		return errors.NotImplemented
+	}
+	_, err := io.WriteString(w, s+"\n")
+	return err
+}
+
+// space drops the white spaces, as found in the dumps of logs.
+func space(r rune) rune {
+	if unicode.IsSpace(r) {
+		return -1
+	}
+	return r
+}
+
+// value returns the value of the field to print: in hexadecimal for binary data
+// and masked if sensitive.
+func value(f field.Field) string {
+	d, ok := f.(*field.Data)
+	if !ok || d.Format != field.Binary {
+		return field.Mask(f)
+	}
+	b, err := d.Bytes()
+	if err != nil {
+		return field.Mask(f)
+	}
+	s := strings.ToUpper(hex.EncodeToString(b))
+	if field.Sensitive(f.ID()) {
+		return strings.Repeat(field.MaskChar, len(s))
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/capture"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/pcap"
+)
+
+// extracted is the JSON representation of a message extracted from a pcap file.
+type extracted struct {
+	Time    time.Time         `json:"time"`
+	Src     string            `json:"src"`
+	Dst     string            `json:"dst"`
+	Message *iso8583.Redacted `json:"message,omitempty"`
+	Err     string            `json:"error,omitempty"`
+}
+
+func extract(args []string, _ io.Reader, stdout io.Writer) error {
+	var (
+		o       = newOptions("pcap", "")
+		framing = o.flags.String("framing", frame.Binary2.String(), "length prefix of the frames: 2b, 4b, 4a or 2bcd")
+		port    = o.flags.Uint("port", 0, "port of the server, all the TCP connections by default")
+		asJSON  = o.flags.Bool("json", false, "prints the messages in JSON")
+		output  = o.flags.String("capture", "", "writes the messages in this capture file, the port is required")
+	)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() != 1 || *port > 1<<16-1 || *output != "" && *port == 0 {
+		return errors.Data
+	}
+	prefix, err := frame.ParsePrefix(*framing)
+	if err != nil {
+		return err
+	}
+	if _, err = o.message(); err != nil {
+		return err
+	}
+	f, err := os.Open(o.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	r := &pcap.Reader{
+		Prefix: prefix,
+		Port:   uint16(*port),
+		New: func() *iso8583.Message {
+			m, _ := o.message()
+			return m
+		},
+	}
+	list, err := r.Read(f)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		if err = record(*output, uint16(*port), list); err != nil {
+			return err
+		}
+	}
+	if *asJSON {
+		out := make([]extracted, len(list))
+		for k, v := range list {
+			out[k] = extracted{Time: v.Time, Src: v.Src.String(), Dst: v.Dst.String()}
+			if v.Err != nil {
+				out[k].Err = v.Err.Error()
+			} else {
+				out[k].Message = &iso8583.Redacted{Message: v.Message}
+			}
+		}
+		return json.NewEncoder(stdout).Encode(out)
+	}
+	for _, v := range list {
+		fmt.Fprintf(stdout, "%s %s -> %s\n", v.Time.Format(time.RFC3339Nano), v.Src, v.Dst)
+		if v.Err != nil {
+			fmt.Fprintf(stdout, "error: %s\n\n", v.Err)
+			continue
+		}
+		if err = printMessage(stdout, v.Message); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
+
+// record writes the frames in a capture file, as seen by the server listening on the port.
+func record(name string, port uint16, list []*pcap.Message) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w, err := capture.NewWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	for _, v := range list {
+		if v.Frame == nil {
+			continue
+		}
+		r := &capture.Record{Time: v.Time, Direction: capture.Inbound, Conn: v.Src.String(), Frame: v.Frame}
+		if v.Src.Port == port {
+			r.Direction, r.Conn = capture.Outbound, v.Dst.String()
+		}
+		if err = w.Write(r); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
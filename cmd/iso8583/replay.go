@@ -0,0 +1,159 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/capture"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/frame"
+)
+
+// replayed is the JSON representation of a replayed request.
+type replayed struct {
+	Conn        string              `json:"conn"`
+	Time        time.Time           `json:"time"`
+	Differences iso8583.Differences `json:"differences,omitempty"`
+	Err         string              `json:"error,omitempty"`
+}
+
+func replay(args []string, _ io.Reader, stdout io.Writer) error {
+	var (
+		o       = newOptions("replay", "")
+		addr    = o.flags.String("addr", "", "address of the server, like localhost:5300")
+		framing = o.flags.String("framing", frame.Binary2.String(), "length prefix of the frames: 2b, 4b, 4a or 2bcd")
+		ignore  = o.flags.String("ignore", ids2s(capture.Volatile), "comma separated list of the fields to ignore")
+		timeout = o.flags.Duration("timeout", 5*time.Second, "maximum duration to wait for a response")
+		asJSON  = o.flags.Bool("json", false, "prints the results in JSON")
+	)
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if *addr == "" || o.flags.NArg() != 1 {
+		return errors.Data
+	}
+	prefix, err := frame.ParsePrefix(*framing)
+	if err != nil {
+		return err
+	}
+	ids, err := s2ids(*ignore)
+	if err != nil {
+		return err
+	}
+	// Checks the options of the messages.
+	if _, err = o.message(); err != nil {
+		return err
+	}
+	records, err := read(o.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	r := &capture.Replayer{
+		Dial: func() (net.Conn, error) {
+			return net.DialTimeout("tcp", *addr, *timeout)
+		},
+		Prefix: prefix,
+		New: func() *iso8583.Message {
+			m, _ := o.message()
+			return m
+		},
+		Ignore:  ids,
+		Timeout: *timeout,
+	}
+	var (
+		res    = r.Replay(records)
+		failed int
+		out    = make([]replayed, len(res))
+	)
+	for k, v := range res {
+		out[k] = replayed{Conn: v.Request.Conn, Time: v.Request.Time, Differences: v.Differences}
+		for i := range out[k].Differences {
+			mask(&out[k].Differences[i])
+		}
+		if v.Err != nil {
+			out[k].Err = v.Err.Error()
+		}
+		if !v.OK() {
+			failed++
+		}
+	}
+	if *asJSON {
+		err = json.NewEncoder(stdout).Encode(out)
+	} else {
+		err = printReplay(stdout, out)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d response(s) differ", failed, len(res))
+	}
+	return nil
+}
+
+func printReplay(w io.Writer, list []replayed) error {
+	for _, v := range list {
+		status := "ok"
+		switch {
+		case v.Err != "":
+			status = "error: " + v.Err
+		case len(v.Differences) > 0:
+			status = "differs"
+		}
+		_, err := fmt.Fprintf(w, "%s %s %s\n%s", v.Time.Format(time.RFC3339Nano), v.Conn, status, v.Differences)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read returns the records of the capture file.
+func read(name string) ([]*capture.Record, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	r, err := capture.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadAll()
+}
+
+// s2ids parses the comma separated list of fields.
+func s2ids(s string) ([]field.ID, error) {
+	var out []field.ID
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || n > iso8583.MaxField {
+			return nil, errors.Data
+		}
+		out = append(out, field.ID(n))
+	}
+	return out, nil
+}
+
+func ids2s(ids []field.ID) string {
+	s := make([]string, len(ids))
+	for k, v := range ids {
+		s[k] = strconv.Itoa(int(v))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/simulator"
+)
+
+func simulate(args []string, _ io.Reader, stdout io.Writer) error {
+	// The scenario defines the format of the messages.
+	var (
+		flags = flag.NewFlagSet("simulate", flag.ContinueOnError)
+		addr  = flags.String("addr", "localhost:5300", "address to listen on")
+	)
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.Data
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	s, err := simulator.Load(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	srv := &simulator.Server{Scenario: s, ErrorLog: log.New(stdout, "", log.LstdFlags)}
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		<-c
+		_ = srv.Close()
+	}()
+	if _, err = fmt.Fprintf(stdout, "listening on %s\n", l.Addr()); err != nil {
+		return err
+	}
+	if err = srv.Serve(l); err != net.ErrClosed {
+		return err
+	}
+	return nil
+}
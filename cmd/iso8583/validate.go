@@ -0,0 +1,23 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// validate decodes the message: the decoding checks its MTI, its fields and that no data remains.
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := newOptions("validate", "input")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if _, err := o.unmarshal(stdin); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "valid")
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+// Change is the kind of a difference.
+type Change uint8
+
+// List of changes.
+const (
+	Changed Change = iota
+	Added
+	Removed
+)
+
+var changes = []string{"changed", "added", "removed"}
+
+// String implements the fmt.Stringer interface.
+func (c Change) String() string {
+	if int(c) < len(changes) {
+		return changes[c]
+	}
+	return ""
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (c Change) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (c *Change) UnmarshalText(text []byte) error {
+	for k, v := range changes {
+		if v == string(text) {
+			*c = Change(k)
+			return nil
+		}
+	}
+	return errors.Data
+}
+
+// Difference is a difference between two messages.
+type Difference struct {
+	Change Change `json:"change"`
+	// Field is the data element, zero for the MTI.
+	Field field.ID `json:"field"`
+	// Path locates the value: "mti", the field like "48" or one of its sub-elements like "55.9F02".
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// String implements the fmt.Stringer interface.
+func (d Difference) String() string {
+	switch d.Change {
+	case Added:
+		return fmt.Sprintf("+ %s: %q", d.Path, d.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %q", d.Path, d.Old)
+	default:
+		return fmt.Sprintf("~ %s: %q -> %q", d.Path, d.Old, d.New)
+	}
+}
+
+// Differences is a list of differences.
+type Differences []Difference
+
+// String implements the fmt.Stringer interface.
+func (d Differences) String() string {
+	var s strings.Builder
+	for _, v := range d {
+		s.WriteString(v.String())
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+// Decomposer splits the value of a composite field into its sub-elements, by path.
+type Decomposer func(value string) (map[string]string, error)
+
+// Composites lists the decomposers of the composite fields.
+type Composites map[field.ID]Decomposer
+
+// DefaultComposites compares the EMV data (field 55) by tag and the private data (field 48) by sub-element.
+var DefaultComposites = Composites{
+	48: TextSubfields(tlv.Subfields),
+	55: HexTLV,
+}
+
+// HexTLV decomposes BER-TLV data written in hexadecimal.
+func HexTLV(value string) (map[string]string, error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, errors.Data
+	}
+	list, err := tlv.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for k, v := range tlv.Flatten(list) {
+		out[k] = strings.ToUpper(hex.EncodeToString(v))
+	}
+	return out, nil
+}
+
+// TextSubfields returns a decomposer of sub-elements with this encoding.
+func TextSubfields(e tlv.Text) Decomposer {
+	return func(value string) (map[string]string, error) {
+		list, err := e.Decode(value)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string)
+		for k, v := range tlv.Flatten(list) {
+			out[k] = string(v)
+		}
+		return out, nil
+	}
+}
+
+// Diff returns the differences between the messages, using the default composites.
+func Diff(a, b *Message) Differences {
+	return DefaultComposites.Diff(a, b)
+}
+
+// Diff returns the differences between the messages: the MTI then the data elements by position.
+// The bitmaps are ignored since they only reflect the presence of the data elements.
+// A composite field is compared by sub-element when both values can be decomposed.
+// The binary values are written in hexadecimal.
+func (c Composites) Diff(a, b *Message) Differences {
+	var out Differences
+	if x, y := a.Type(), b.Type(); x != y {
+		out = append(out, Difference{Change: Changed, Path: "mti", Old: x, New: y})
+	}
+	for _, id := range union(a, b) {
+		x, inA := a.Data[id]
+		y, inB := b.Data[id]
+		path := strconv.Itoa(int(id))
+		switch {
+		case !inA:
+			out = append(out, Difference{Change: Added, Field: id, Path: path, New: text(y, false)})
+		case !inB:
+			out = append(out, Difference{Change: Removed, Field: id, Path: path, Old: text(x, false)})
+		case x.String() != y.String():
+			out = append(out, c.diff(id, text(x, false), text(y, false))...)
+		}
+	}
+	return out
+}
+
+func (c Composites) diff(id field.ID, x, y string) Differences {
+	var (
+		path = strconv.Itoa(int(id))
+		all  = Differences{{Change: Changed, Field: id, Path: path, Old: x, New: y}}
+	)
+	fn, ok := c[id]
+	if !ok {
+		return all
+	}
+	a, err := fn(x)
+	if err != nil {
+		return all
+	}
+	b, err := fn(y)
+	if err != nil {
+		return all
+	}
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var out Differences
+	for _, k := range keys {
+		v, inA := a[k]
+		w, inB := b[k]
+		d := Difference{Field: id, Path: path + "." + k, Old: v, New: w}
+		switch {
+		case !inA:
+			d.Change = Added
+		case !inB:
+			d.Change = Removed
+		case v == w:
+			continue
+		}
+		out = append(out, d)
+	}
+	if len(out) == 0 {
+		// Same sub-elements with another encoding, like the order of the tags.
+		return all
+	}
+	return out
+}
+
+// union returns the sorted positions of the data elements of both messages, except the bitmap.
+func union(a, b *Message) []field.ID {
+	var (
+		list = append(a.ids(), b.ids()...)
+		out  []field.ID
+	)
+	sort.Ints(list)
+	for k, v := range list {
+		if k == 0 || list[k-1] != v {
+			out = append(out, field.ID(v))
+		}
+	}
+	return out
+}
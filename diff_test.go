@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/field"
+)
+
+func newMessage(mti string, values map[field.ID]string) *iso8583.Message {
+	m := &iso8583.Message{Data: iso8583.Fields{}}
+	m.MTI, _ = iso8583.ParseMTI(mti)
+	for id, v := range values {
+		f := field.New(id)
+		f.Value = []byte(v)
+		if f.Type != field.Fixed {
+			f.Size = len(v)
+		}
+		m.Data[id] = f
+	}
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		are = is.New(t)
+		a   = newMessage("0100", map[field.ID]string{
+			3:  "000000",
+			11: "000001",
+			48: "0103abc4202XY",
+			55: "9F02060000000010009F360200FF",
+		})
+		b = newMessage("0110", map[field.ID]string{
+			3:  "000000",
+			39: "00",
+			48: "0103abd",
+			55: "9F02060000000020009F360200FF5F2A020978",
+		})
+	)
+	out := iso8583.Diff(a, b)
+	are.Equal(out, iso8583.Differences{
+		{Change: iso8583.Changed, Path: "mti", Old: "0100", New: "0110"},
+		{Change: iso8583.Removed, Field: 11, Path: "11", Old: "000001"},
+		{Change: iso8583.Added, Field: 39, Path: "39", New: "00"},
+		{Change: iso8583.Changed, Field: 48, Path: "48.01", Old: "abc", New: "abd"},
+		{Change: iso8583.Removed, Field: 48, Path: "48.42", Old: "XY"},
+		{Change: iso8583.Added, Field: 55, Path: "55.5F2A", New: "0978"},
+		{Change: iso8583.Changed, Field: 55, Path: "55.9F02", Old: "000000001000", New: "000000002000"},
+	})
+	are.Equal(out.String(), `~ mti: "0100" -> "0110"
+- 11: "000001"
++ 39: "00"
+~ 48.01: "abc" -> "abd"
+- 48.42: "XY"
++ 55.5F2A: "0978"
+~ 55.9F02: "000000001000" -> "000000002000"
+`)
+	j, err := json.Marshal(out[:2])
+	are.NoErr(err)
+	are.Equal(string(j), `[{"change":"changed","field":0,"path":"mti","old":"0100","new":"0110"},`+
+		`{"change":"removed","field":11,"path":"11","old":"000001"}]`)
+
+	// Without composites or with undecodable values.
+	c := newMessage("0100", map[field.ID]string{48: "0103abc4202XZ", 55: "9F"})
+	are.Equal(iso8583.Composites{}.Diff(a, c)[2], iso8583.Difference{
+		Change: iso8583.Changed, Field: 48, Path: "48", Old: "0103abc4202XY", New: "0103abc4202XZ",
+	})
+	are.Equal(iso8583.Diff(a, c)[3].Path, "55")
+	are.Equal(len(iso8583.Diff(a, a)), 0)
+
+	// The binary values are in hexadecimal.
+	x, y := field.New(52), field.New(52)
+	are.NoErr(x.SetBytes([]byte{0x1B, 0x9C, 0x18, 0x45, 0xEB, 0x99, 0x3A, 0x7A}))
+	are.NoErr(y.SetBytes([]byte{0x1B, 0x9C, 0x18, 0x45, 0xEB, 0x99, 0x3A, 0x7B}))
+	c.Data[52] = x
+	d := newMessage("0100", map[field.ID]string{48: "0103abc4202XZ", 55: "9F"})
+	d.Data[52] = y
+	are.Equal(iso8583.Diff(c, d).String(), "~ 52: \"1B9C1845EB993A7A\" -> \"1B9C1845EB993A7B\"\n")
+	delete(d.Data, 52)
+	are.Equal(iso8583.Diff(c, d).String(), "- 52: \"1B9C1845EB993A7A\"\n")
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package dukpt
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// KeyType is the type of an AES DUKPT key.
+type KeyType uint16
+
+// List of key types, using the algorithm indicators of X9.24-3.
+const (
+	TDES2 KeyType = iota
+	TDES3
+	AES128
+	AES192
+	AES256
+)
+
+// Len returns the length of the key in bytes.
+func (t KeyType) Len() int {
+	switch t {
+	case TDES2, AES128:
+		return 16
+	case TDES3, AES192:
+		return 24
+	case AES256:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// keyType returns the AES key type matching the key length.
+func keyType(key []byte) (KeyType, error) {
+	switch len(key) {
+	case 16:
+		return AES128, nil
+	case 24:
+		return AES192, nil
+	case 32:
+		return AES256, nil
+	default:
+		return 0, errors.Key
+	}
+}
+
+// List of key usage indicators.
+const (
+	usagePIN           = 0x1000
+	usageMACGeneration = 0x2000
+	usageMACVerify     = 0x2001
+	usageDataEncrypt   = 0x3000
+	usageDataDecrypt   = 0x3001
+	usageDerivation    = 0x8000
+	usageInitialKey    = 0x8001
+)
+
+var usages = map[Usage]uint16{
+	PIN:          usagePIN,
+	MACRequest:   usageMACGeneration,
+	MACResponse:  usageMACVerify,
+	DataRequest:  usageDataEncrypt,
+	DataResponse: usageDataDecrypt,
+}
+
+// AESWorkingKey returns the key of this type for this usage and this transaction, derived from the AES initial key.
+// It allows to derive a working key with a type different from the one of the initial key.
+func AESWorkingKey(ik []byte, ksn KSN, u Usage, t KeyType) ([]byte, error) {
+	if _, err := keyType(ik); err != nil {
+		return nil, err
+	}
+	return aesWorkingKey(ik, ksn, u, t)
+}
+
+// aesInitialKey returns the initial key derived from the BDK with the initial key ID of the KSN.
+func aesInitialKey(bdk []byte, ksn KSN, t KeyType) ([]byte, error) {
+	if !ksn.AES() {
+		return nil, errors.Length
+	}
+	return derive(bdk, data(usageInitialKey, t, ksn, 0), t)
+}
+
+func aesWorkingKey(ik []byte, ksn KSN, u Usage, t KeyType) ([]byte, error) {
+	usage, ok := usages[u]
+	if !ok || t.Len() == 0 {
+		return nil, errors.NotImplemented
+	}
+	if !ksn.AES() {
+		return nil, errors.Length
+	}
+	var (
+		key     = ik
+		counter = ksn.Counter()
+		work    uint32
+		err     error
+	)
+	if counter == 0 {
+		return nil, errors.Data
+	}
+	it, err := keyType(ik)
+	if err != nil {
+		return nil, err
+	}
+	for bit := uint32(1 << 31); bit > 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		work |= bit
+		key, err = derive(key, data(usageDerivation, it, ksn, work), it)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return derive(key, data(usage, t, ksn, counter), t)
+}
+
+// data returns the 16-byte derivation data.
+func data(usage uint16, t KeyType, ksn KSN, counter uint32) []byte {
+	b := make([]byte, aes.BlockSize)
+	b[0] = 1 // Version
+	b[1] = 1 // Key block counter
+	binary.BigEndian.PutUint16(b[2:], usage)
+	binary.BigEndian.PutUint16(b[4:], uint16(t))
+	binary.BigEndian.PutUint16(b[6:], uint16(t.Len()*8))
+	if usage == usageInitialKey {
+		// Initial key ID.
+		copy(b[8:], ksn[:8])
+		return b
+	}
+	// Derivation ID of the initial key ID then the counter.
+	copy(b[8:], ksn[4:8])
+	binary.BigEndian.PutUint32(b[12:], counter)
+
+	return b
+}
+
+// derive enciphers the derivation data with the key as many times as needed to build a key of this type.
+func derive(key, data []byte, t KeyType) ([]byte, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Key
+	}
+	out := make([]byte, (t.Len()+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	for i := 0; i < len(out); i += aes.BlockSize {
+		data[1] = byte(i/aes.BlockSize + 1)
+		c.Encrypt(out[i:], data)
+	}
+	return out[:t.Len()], nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package dukpt implements the Derived Unique Key Per Transaction key management defined in ANSI X9.24:
+// the TDES DUKPT of X9.24-1 with a 10-byte key serial number
+// and the AES DUKPT of X9.24-3 with a 12-byte key serial number.
+//
+// The key serial number (KSN) is usually sent in the field 53 or in a private field.
+// The derived keys enciphers the PIN block of the field 52 (see the pin package)
+// and compute the MAC of the field 64 or 128 (see the mac package).
+package dukpt
+
+import (
+	"encoding/hex"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Usage is the purpose of a working key.
+type Usage uint8
+
+// List of key usages.
+const (
+	// PIN enciphers the PIN block.
+	PIN Usage = iota
+	// MACRequest generates the MAC of the request, or in both ways with TDES DUKPT.
+	MACRequest
+	// MACResponse verifies the MAC of the response.
+	MACResponse
+	// DataRequest enciphers the data of the request, or in both ways with TDES DUKPT.
+	DataRequest
+	// DataResponse deciphers the data of the response.
+	DataResponse
+)
+
+// Lengths of the key serial numbers.
+const (
+	TDESLen = 10
+	AESLen  = 12
+)
+
+// KSN is a key serial number: the identifier of the initial key of the device followed by the transaction counter.
+type KSN []byte
+
+// ParseKSN parses the hexadecimal representation of a TDES or AES key serial number.
+func ParseKSN(s string) (KSN, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.Data
+	}
+	k := KSN(b)
+	if !k.Valid() {
+		return nil, errors.Length
+	}
+	return k, nil
+}
+
+// Valid returns in success if the length of the KSN is the one of a TDES or AES key serial number.
+func (k KSN) Valid() bool {
+	return len(k) == TDESLen || len(k) == AESLen
+}
+
+// AES returns in success if it is an AES DUKPT key serial number.
+func (k KSN) AES() bool {
+	return len(k) == AESLen
+}
+
+// Counter returns the transaction counter: the 21 right-most bits for TDES, the 32 right-most bits for AES.
+func (k KSN) Counter() uint32 {
+	if !k.Valid() {
+		return 0
+	}
+	n := len(k)
+	c := uint32(k[n-4])<<24 | uint32(k[n-3])<<16 | uint32(k[n-2])<<8 | uint32(k[n-1])
+	if !k.AES() {
+		c &= tdesCounter
+	}
+	return c
+}
+
+// WithCounter returns a copy of the KSN using this transaction counter.
+func (k KSN) WithCounter(c uint32) KSN {
+	dst := make(KSN, len(k))
+	copy(dst, k)
+	if !k.Valid() {
+		return dst
+	}
+	n := len(k)
+	if !k.AES() {
+		c = c&tdesCounter | (uint32(k[n-3])<<16)&^tdesCounter
+		dst[n-3], dst[n-2], dst[n-1] = byte(c>>16), byte(c>>8), byte(c)
+		return dst
+	}
+	dst[n-4], dst[n-3], dst[n-2], dst[n-1] = byte(c>>24), byte(c>>16), byte(c>>8), byte(c)
+	return dst
+}
+
+// String implements the fmt.Stringer interface.
+func (k KSN) String() string {
+	return hex.EncodeToString(k)
+}
+
+// InitialKey returns the initial key loaded in the device, derived from the base derivation key (BDK):
+// the IPEK for TDES DUKPT, the initial key of the same type than the BDK for AES DUKPT.
+func InitialKey(bdk []byte, ksn KSN) ([]byte, error) {
+	switch len(ksn) {
+	case TDESLen:
+		return ipek(bdk, ksn)
+	case AESLen:
+		t, err := keyType(bdk)
+		if err != nil {
+			return nil, err
+		}
+		return aesInitialKey(bdk, ksn, t)
+	default:
+		return nil, errors.Length
+	}
+}
+
+// WorkingKey returns the key for this usage and this transaction, derived from the initial key.
+// AES working keys have the same type than the initial key.
+func WorkingKey(ik []byte, ksn KSN, u Usage) ([]byte, error) {
+	switch len(ksn) {
+	case TDESLen:
+		return tdesWorkingKey(ik, ksn, u)
+	case AESLen:
+		t, err := keyType(ik)
+		if err != nil {
+			return nil, err
+		}
+		return aesWorkingKey(ik, ksn, u, t)
+	default:
+		return nil, errors.Length
+	}
+}
+
+// Derive returns the key for this usage and this transaction, derived from the base derivation key.
+// It's the method used by the host.
+func Derive(bdk []byte, ksn KSN, u Usage) ([]byte, error) {
+	ik, err := InitialKey(bdk, ksn)
+	if err != nil {
+		return nil, err
+	}
+	return WorkingKey(ik, ksn, u)
+}
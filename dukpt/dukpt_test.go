@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package dukpt_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/dukpt"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/pin"
+)
+
+// Test vectors published with ANSI X9.24-1 and X9.24-3.
+const (
+	tdesBDK = "0123456789ABCDEFFEDCBA9876543210"
+	tdesKSN = "FFFF9876543210E00000"
+	aesBDK  = "FEDCBA9876543210F1F1F1F1F1F1F1F1"
+	aesKSN  = "123456789012345600000001"
+	pan     = "4012345678909"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestParseKSN(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			in      string
+			counter uint32
+			aes     bool
+			err     error
+		}{
+			{in: tdesKSN},
+			{in: "FFFF9876543210FFFFFF", counter: 1<<21 - 1},
+			{in: aesKSN, counter: 1, aes: true},
+			{in: "FFFF9876543210E0", err: errors.Length},
+			{in: "KSN", err: errors.Data},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := dukpt.ParseKSN(tt.in)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			are.Equal(out.Counter(), tt.counter)
+			are.Equal(out.AES(), tt.aes)
+			are.Equal(strings.ToUpper(out.String()), tt.in)
+			are.Equal(out.WithCounter(7).Counter(), uint32(7))
+			are.Equal(out.WithCounter(7)[:len(out)-4], out[:len(out)-4])
+		})
+	}
+}
+
+func TestDerive(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			bdk, ksn string
+			usage    dukpt.Usage
+			ik, key  string
+			err      error
+		}{
+			{
+				bdk:   tdesBDK,
+				ksn:   "FFFF9876543210E00001",
+				usage: dukpt.PIN,
+				ik:    "6AC292FAA1315B4D858AB3A3D7D5933A",
+				key:   "042666B49184CF5C68DE9628D0397B36",
+			},
+			{
+				bdk:   tdesBDK,
+				ksn:   "FFFF9876543210E00001",
+				usage: dukpt.MACRequest,
+				ik:    "6AC292FAA1315B4D858AB3A3D7D5933A",
+				key:   "042666B4918430A368DE9628D03984C9",
+			},
+			{
+				bdk:   tdesBDK,
+				ksn:   "FFFF9876543210E00001",
+				usage: dukpt.MACResponse,
+				ik:    "6AC292FAA1315B4D858AB3A3D7D5933A",
+				key:   "042666B46E84CFA368DE96282F397BC9",
+			},
+			{
+				bdk:   aesBDK,
+				ksn:   aesKSN,
+				usage: dukpt.PIN,
+				ik:    "1273671EA26AC29AFA4D1084127652A1",
+				key:   "AF8CB133A78F8DC2D1359F18527593FB",
+			},
+			{bdk: tdesBDK, ksn: tdesKSN, usage: dukpt.PIN, err: errors.Data},
+			{bdk: tdesBDK[:16], ksn: tdesKSN, usage: dukpt.PIN, err: errors.Key},
+			{bdk: aesBDK[:8], ksn: aesKSN, usage: dukpt.PIN, err: errors.Key},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			ksn, err := dukpt.ParseKSN(tt.ksn)
+			are.NoErr(err)
+			key, err := dukpt.Derive(unhex(tt.bdk), ksn, tt.usage)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			are.Equal(strings.ToUpper(hex.EncodeToString(key)), tt.key)
+			ik, err := dukpt.InitialKey(unhex(tt.bdk), ksn)
+			are.NoErr(err)
+			are.Equal(strings.ToUpper(hex.EncodeToString(ik)), tt.ik)
+		})
+	}
+}
+
+func TestWorkingKey(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			counter uint32
+			block   string
+		}{
+			{counter: 1, block: "1B9C1845EB993A7A"},
+			{counter: 2, block: "10A01C8D02C69107"},
+			{counter: 3, block: "18DC07B94797B466"},
+		}
+	)
+	ksn, err := dukpt.ParseKSN(tdesKSN)
+	are.NoErr(err)
+	ik, err := dukpt.InitialKey(unhex(tdesBDK), ksn)
+	are.NoErr(err)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			key, err := dukpt.WorkingKey(ik, ksn.WithCounter(tt.counter), dukpt.PIN)
+			are.NoErr(err)
+			out, err := pin.ISO0.Encrypt(key, "1234", pan)
+			are.NoErr(err)
+			are.Equal(strings.ToUpper(hex.EncodeToString(out)), tt.block)
+		})
+	}
+}
+
+func TestAESWorkingKey(t *testing.T) {
+	are := is.New(t)
+	ksn, err := dukpt.ParseKSN(aesKSN)
+	are.NoErr(err)
+	ik, err := dukpt.InitialKey(unhex(aesBDK), ksn)
+	are.NoErr(err)
+	key, err := dukpt.AESWorkingKey(ik, ksn, dukpt.PIN, dukpt.AES256)
+	are.NoErr(err)
+	are.Equal(len(key), 32)
+	// Enciphers a PIN block in format 4.
+	out, err := pin.ISO4.Encrypt(key, "1234", pan)
+	are.NoErr(err)
+	p, err := pin.ISO4.Decrypt(key, out, pan)
+	are.NoErr(err)
+	are.Equal(p, "1234")
+
+	_, err = dukpt.AESWorkingKey(ik, ksn, dukpt.Usage(42), dukpt.AES128)
+	are.Equal(err, errors.NotImplemented)
+}
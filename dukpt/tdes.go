@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package dukpt
+
+import (
+	"crypto/des"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/internal/des3"
+)
+
+// tdesCounter masks the 21-bit transaction counter.
+const tdesCounter = 1<<21 - 1
+
+var (
+	// keyMask is applied on a key to derive the left half of the IPEK, or of the future keys.
+	keyMask = []byte{0xC0, 0xC0, 0xC0, 0xC0, 0x00, 0x00, 0x00, 0x00, 0xC0, 0xC0, 0xC0, 0xC0, 0x00, 0x00, 0x00, 0x00}
+	// variants lists the masks to apply on the current key by usage.
+	variants = map[Usage][]byte{
+		PIN:          {0, 0, 0, 0, 0, 0, 0, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0xFF},
+		MACRequest:   {0, 0, 0, 0, 0, 0, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0},
+		MACResponse:  {0, 0, 0, 0, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0, 0, 0},
+		DataRequest:  {0, 0, 0, 0, 0, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0, 0},
+		DataResponse: {0, 0, 0, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0, 0, 0, 0},
+	}
+)
+
+// ipek returns the initial PIN encryption key (IPEK) derived from the double length BDK.
+func ipek(bdk []byte, ksn KSN) ([]byte, error) {
+	if len(bdk) != 2*des.BlockSize {
+		return nil, errors.Key
+	}
+	// Uses the 8 left-most bytes of the KSN, without the counter.
+	reg := ksn.WithCounter(0)[:des.BlockSize]
+	left, err := encrypt(bdk, reg)
+	if err != nil {
+		return nil, err
+	}
+	right, err := encrypt(xor(bdk, keyMask), reg)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// tdesWorkingKey derives the current key with a non-reversible function by bit set in the counter,
+// then applies the usage's variant.
+func tdesWorkingKey(ik []byte, ksn KSN, u Usage) ([]byte, error) {
+	variant, ok := variants[u]
+	if !ok {
+		return nil, errors.NotImplemented
+	}
+	if len(ik) != 2*des.BlockSize {
+		return nil, errors.Key
+	}
+	var (
+		key     = ik
+		counter = ksn.Counter()
+		reg     = register(ksn.WithCounter(0))
+		err     error
+	)
+	if counter == 0 {
+		return nil, errors.Data
+	}
+	for bit := uint32(1 << 20); bit > 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		reg[5] |= byte(bit >> 16)
+		reg[6] |= byte(bit >> 8)
+		reg[7] |= byte(bit)
+		key, err = nonReversible(key, reg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	key = xor(key, variant)
+	if u != DataRequest && u != DataResponse {
+		return key, nil
+	}
+	// The data keys are enciphered with themselves.
+	left, err := encrypt(key, key[:des.BlockSize])
+	if err != nil {
+		return nil, err
+	}
+	right, err := encrypt(key, key[des.BlockSize:])
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// nonReversible returns the key derived from the current one and the KSN register.
+func nonReversible(key, reg []byte) ([]byte, error) {
+	half := func(k []byte) ([]byte, error) {
+		c, err := des.NewCipher(k[:des.BlockSize])
+		if err != nil {
+			return nil, err
+		}
+		out := xor(reg, k[des.BlockSize:])
+		c.Encrypt(out, out)
+		return xor(out, k[des.BlockSize:]), nil
+	}
+	right, err := half(key)
+	if err != nil {
+		return nil, err
+	}
+	left, err := half(xor(key, keyMask))
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// register returns the 8 right-most bytes of the KSN.
+func register(ksn KSN) []byte {
+	reg := make([]byte, des.BlockSize)
+	copy(reg, ksn[len(ksn)-des.BlockSize:])
+	return reg
+}
+
+func encrypt(key, src []byte) ([]byte, error) {
+	c, err := des3.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return des3.EncryptECB(c, src)
+}
+
+func xor(a, b []byte) []byte {
+	dst := make([]byte, len(a))
+	for i := range a {
+		dst[i] = a[i] ^ b[i]
+	}
+	return dst
+}
@@ -6,16 +6,21 @@ package encoding
 
 import (
 	"encoding/hex"
-	"fmt"
 )
 
 // Converts the bytes as binary data.
 func Binary(src []byte) []byte {
-	var s string
+	return AppendBinary(make([]byte, 0, len(src)*8), src)
+}
+
+// AppendBinary appends the bytes as binary data to dst and returns the extended buffer.
+func AppendBinary(dst, src []byte) []byte {
 	for _, c := range src {
-		s = fmt.Sprintf("%s%.*b", s, 8, c)
+		for k := 7; k >= 0; k-- {
+			dst = append(dst, '0'+c>>uint(k)&1)
+		}
 	}
-	return []byte(s)
+	return dst
 }
 
 // MustBCD encodes the data to BCD and panics if it failed to do it.
@@ -26,6 +26,8 @@ func Parse(format string) (Format, error) {
 		return ASCII, nil
 	case "BCD":
 		return BCD, nil
+	case "EBCDIC":
+		return EBCDIC, nil
 	default:
 		return 0, errors.NotImplemented
 	}
@@ -40,8 +42,41 @@ const (
 	ASCII Format = iota
 	// BCD aka. Binary Coded Decimal format.
 	BCD
+	// EBCDIC aka. Extended Binary Coded Decimal Interchange Code (code page 037).
+	// The bitmap is written in hexadecimal like the other characters.
+	EBCDIC
 )
 
+// String implements the fmt.Stringer interface.
+func (e Format) String() string {
+	switch e {
+	case ASCII:
+		return "ascii"
+	case BCD:
+		return "bcd"
+	case EBCDIC:
+		return "ebcdic"
+	default:
+		return ""
+	}
+}
+
+// FromASCII converts the ASCII text to the character set of the format.
+func (e Format) FromASCII(src []byte) []byte {
+	if e == EBCDIC {
+		return ASCIIToEBCDIC(src)
+	}
+	return src
+}
+
+// ToASCII converts the text from the character set of the format to ASCII.
+func (e Format) ToASCII(src []byte) []byte {
+	if e == EBCDIC {
+		return EBCDICToASCII(src)
+	}
+	return src
+}
+
 // DecodeBCD decodes the data as Binary code decimal.
 func (e Format) DecodeBCD(src []byte) []byte {
 	switch e {
@@ -67,6 +102,25 @@ func (e Format) DecodeASCII(src []byte) ([]byte, error) {
 	return nil, errors.NotImplemented
 }
 
+// DecodeDecimal returns the decimal as encoded in a header with this format.
+// It is the reverse of EncodeToDecimal.
+func (e Format) DecodeDecimal(n uint64) ([]byte, error) {
+	s := strconv.FormatUint(n, 10)
+	if len(s) > LenHeader {
+		return nil, errors.OutOfRange
+	}
+	s = strings.Repeat("0", LenHeader-len(s)) + s
+	switch e {
+	case ASCII:
+		return []byte(s), nil
+	case BCD:
+		return ASCII.EncodeToBCD([]byte(s))
+	case EBCDIC:
+		return ASCIIToEBCDIC([]byte(s)), nil
+	}
+	return nil, errors.NotImplemented
+}
+
 // EncodeToBCD encodes the data to BCD.
 func (e Format) EncodeToBCD(src []byte) ([]byte, error) {
 	switch e {
@@ -84,19 +138,44 @@ func (e Format) EncodeToBCD(src []byte) ([]byte, error) {
 }
 
 // EncodeToBinary encodes the src to Binary.
+// With EBCDIC, the source is expected to be already translated to ASCII.
 func (e Format) EncodeToBinary(src []byte) ([]byte, error) {
+	return e.AppendBinary(make([]byte, 0, len(src)*4), src)
+}
+
+// AppendBinary encodes the src to Binary, appends it to dst and returns the extended buffer.
+// It allows to reuse the memory of dst.
+func (e Format) AppendBinary(dst, src []byte) ([]byte, error) {
 	switch e {
-	case ASCII, BCD:
-		dst := make([]byte, hex.DecodedLen(len(src)))
-		n, err := hex.Decode(dst, src)
-		if err != nil {
-			return nil, err
+	case ASCII, BCD, EBCDIC:
+		if len(src)%2 != 0 {
+			return nil, hex.ErrLength
 		}
-		return Binary(dst[:n]), nil
+		for _, c := range src {
+			n, ok := nibble(c)
+			if !ok {
+				return nil, hex.InvalidByteError(c)
+			}
+			dst = append(dst, '0'+n>>3&1, '0'+n>>2&1, '0'+n>>1&1, '0'+n&1)
+		}
+		return dst, nil
 	}
 	return nil, errors.NotImplemented
 }
 
+// nibble returns the value of the hexadecimal digit.
+func nibble(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
 // EncodeToDecimal encodes to decimal.
 func (e Format) EncodeToDecimal(src []byte) (uint64, error) {
 	switch e {
@@ -104,6 +183,8 @@ func (e Format) EncodeToDecimal(src []byte) (uint64, error) {
 		return strconv.ParseUint(string(src), 10, 64)
 	case BCD:
 		return strconv.ParseUint(string(ASCII.DecodeBCD(src)), 10, 64)
+	case EBCDIC:
+		return strconv.ParseUint(string(EBCDICToASCII(src)), 10, 64)
 	}
 	return 0, errors.NotImplemented
 }
@@ -111,7 +192,7 @@ func (e Format) EncodeToDecimal(src []byte) (uint64, error) {
 // LenBitmap returns the length of a bitmap.
 func (e Format) LenBitmap() int {
 	switch e {
-	case ASCII, BCD:
+	case ASCII, BCD, EBCDIC:
 		return LenBitmap
 	default:
 		return 0
@@ -121,7 +202,7 @@ func (e Format) LenBitmap() int {
 // LenHeader returns the length of a header.
 func (e Format) LenHeader() int {
 	switch e {
-	case ASCII:
+	case ASCII, EBCDIC:
 		return LenHeader
 	case BCD:
 		return LenHeader / 2
@@ -133,7 +214,7 @@ func (e Format) LenHeader() int {
 // LenMTI returns the length of a MTI.
 func (e Format) LenMTI() int {
 	switch e {
-	case ASCII, BCD:
+	case ASCII, BCD, EBCDIC:
 		return LenMTI
 	default:
 		return 0
@@ -25,6 +25,7 @@ func TestParse(t *testing.T) {
 			{in: "ASCII", out: encoding.ASCII},
 			{in: "bcd", out: encoding.BCD},
 			{in: "BCD", out: encoding.BCD},
+			{in: "ebcdic", out: encoding.EBCDIC},
 			{in: txt, err: errors.NotImplemented},
 		}
 	)
@@ -47,6 +48,7 @@ func TestFormat(t *testing.T) {
 		}{
 			{fmt: encoding.ASCII, bitmap: 16, header: 4, mti: 4},
 			{fmt: encoding.BCD, bitmap: 16, header: 2, mti: 4},
+			{fmt: encoding.EBCDIC, bitmap: 16, header: 4, mti: 4},
 			{fmt: 255},
 		}
 	)
@@ -71,6 +73,7 @@ func TestFormat_EncodeToDecimal(t *testing.T) {
 		}{
 			{fmt: encoding.ASCII, in: "10", out: 10},
 			{fmt: encoding.BCD, in: "10", out: 3130},
+			{fmt: encoding.EBCDIC, in: "\xF1\xF0", out: 10},
 			{fmt: 255, err: errors.NotImplemented},
 		}
 	)
@@ -83,3 +86,37 @@ func TestFormat_EncodeToDecimal(t *testing.T) {
 		})
 	}
 }
+
+func TestFormat_AppendBinary(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			fmt     encoding.Format
+			dst, in string
+			out     string
+			err     bool
+		}{
+			{fmt: encoding.ASCII, in: "A02f", out: "1010000000101111"},
+			{fmt: encoding.EBCDIC, dst: "1", in: "80", out: "110000000"},
+			{fmt: encoding.ASCII, in: "A0G0", err: true},
+			{fmt: encoding.ASCII, in: "A02", err: true},
+			{fmt: 255, in: "A0", err: true},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tt.fmt.AppendBinary([]byte(tt.dst), []byte(tt.in))
+			are.Equal(err != nil, tt.err)
+			are.Equal(string(out), tt.out)
+		})
+	}
+}
+
+func TestEBCDICToASCII(t *testing.T) {
+	are := is.New(t)
+	out := encoding.ASCIIToEBCDIC([]byte("0800 Az"))
+	are.Equal(out, []byte{0xF0, 0xF8, 0xF0, 0xF0, 0x40, 0xC1, 0xA9})
+	are.Equal(string(encoding.EBCDICToASCII(out)), "0800 Az")
+	are.Equal(string(encoding.EBCDIC.ToASCII(encoding.EBCDIC.FromASCII([]byte("0800")))), "0800")
+}
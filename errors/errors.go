@@ -7,20 +7,29 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // List of known errors.
 var (
 	// Field is returned if the data is invalid.
 	Data = errors.New("invalid data")
+	// Declined is returned if the partner has declined the request.
+	Declined = errors.New("declined")
+	// Key is returned if the cryptographic key is invalid.
+	Key = errors.New("invalid key")
 	// Length is returned if the length not matches with the expected length.
 	Length = errors.New("invalid length")
+	// MAC is returned if the message authentication code does not match.
+	MAC = errors.New("invalid message authentication code")
 	// MTI is returned if we failed to fields the data.
 	MTI = errors.New("invalid message type identifier")
 	// NotImplemented is returned if the method is not implemented yet.
 	NotImplemented = errors.New("not implemented")
 	// OutOfRange is the data exceeds the bounds.
 	OutOfRange = errors.New("out of range")
+	// PIN is returned if the personal identification number is invalid.
+	PIN = errors.New("invalid PIN")
 )
 
 // New returns a new instance of a field error.
@@ -38,3 +47,15 @@ type Field struct {
 func (e *Field) Error() string {
 	return fmt.Sprintf("field #%d: %s", e.num, e.err)
 }
+
+// List is a list of errors, like the ones of the fields of a message.
+type List []error
+
+// Error implements the error interface.
+func (l List) Error() string {
+	s := make([]string, len(l))
+	for k, err := range l {
+		s[k] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field
+
+import (
+	"time"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// List of supported date formats
+const (
+	timeFmt      = "150405"
+	monthDayFmt  = "0102"
+	yearFmt      = "06"
+	yearMonthFmt = yearFmt + "01"
+	dateFmt      = yearFmt + monthDayFmt
+	// leapYear is used to parse a month and a day without knowing the year, even the 29th February.
+	leapYear = "2000"
+)
+
+const day = 24 * time.Hour
+
+// Window delimits the period around the reference time where a date without year is expected.
+// A window should not exceed 366 days, otherwise the closest date to the reference time wins.
+type Window struct {
+	// Before is the maximum duration before the reference time.
+	Before time.Duration
+	// After is the maximum duration after the reference time.
+	After time.Duration
+}
+
+// List of common windows.
+var (
+	// Past is biased toward the past, as expected for a transmission or a capture date.
+	Past = Window{Before: 336 * day, After: 30 * day}
+	// Future is biased toward the future, as expected for a settlement date.
+	Future = Window{Before: 30 * day, After: 336 * day}
+	// Nearest is centered on the reference time.
+	Nearest = Window{Before: 183 * day, After: 183 * day}
+)
+
+// windows lists the default window of the fields using a date without year.
+// Others use the Nearest one.
+var windows = map[ID]Window{
+	7:  Past,    // Transmission date & time
+	13: Past,    // Date, local transaction
+	15: Future,  // Date, settlement
+	16: Nearest, // Date, conversion
+	17: Past,    // Date, capture
+}
+
+// locals lists the fields expressed in the local time of the card acceptor.
+var locals = map[ID]bool{
+	12: true, // Time, local transaction
+	13: true, // Date, local transaction
+}
+
+// Calendar resolves the data elements using partial dates, like MMDD or MMDDhhmmss, as time.Time.
+// It also composes them from a time.Time.
+// The zero value is ready to use: the reference time is now and the local time zone is UTC.
+type Calendar struct {
+	// Now returns the reference time. If nil, time.Now is used.
+	Now func() time.Time
+	// Local is the time zone of the local transaction date and time (fields 12 and 13).
+	// If nil, UTC is used.
+	Local *time.Location
+	// Windows overrides the default window of a field.
+	Windows map[ID]Window
+}
+
+// Resolve returns the data value as a time.Time.
+// When the year is missing, it takes the one placing the date in the field's window around the reference time.
+func (c *Calendar) Resolve(d *Data) (time.Time, error) {
+	if d.Value == nil || d.Size == 0 {
+		return time.Time{}, errors.Data
+	}
+	layout := layout(d.Format)
+	if layout == "" {
+		return time.Time{}, errors.Data
+	}
+	loc := c.location(d.Pos)
+	if d.Format&MonthDay == 0 {
+		return time.ParseInLocation(layout, d.String(), loc)
+	}
+	t, err := time.ParseInLocation("2006"+layout, leapYear+d.String(), loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.year(t, d.Pos)
+}
+
+// Compose sets the data value with the given time, formatted as expected by the field.
+// The local transaction date and time are converted in the local time zone, others in UTC.
+func (c *Calendar) Compose(d *Data, t time.Time) error {
+	layout := layout(d.Format)
+	if layout == "" {
+		return errors.Data
+	}
+	v := t.In(c.location(d.Pos)).Format(layout)
+	if d.Type == Fixed && d.Size > 0 && len(v) != d.Size {
+		return errors.Length
+	}
+	d.Value = []byte(v)
+	d.Size = len(v)
+
+	return nil
+}
+
+func (c *Calendar) location(id ID) *time.Location {
+	if c.Local != nil && locals[id] {
+		return c.Local
+	}
+	return time.UTC
+}
+
+func (c *Calendar) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+func (c *Calendar) window(id ID) Window {
+	if w, ok := c.Windows[id]; ok {
+		return w
+	}
+	if w, ok := windows[id]; ok {
+		return w
+	}
+	return Nearest
+}
+
+// year returns the date of t in the year placing it in the window of the field.
+// The 29th February is only accepted in leap years.
+func (c *Calendar) year(t time.Time, id ID) (time.Time, error) {
+	var (
+		ref  = c.now().In(t.Location())
+		w    = c.window(id)
+		res  time.Time
+		best time.Duration = -1
+	)
+	for y := ref.Year() - 1; y <= ref.Year()+1; y++ {
+		v := time.Date(y, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+		if v.Day() != t.Day() {
+			// Non-leap year: the 29th February has been normalized to the 1st March.
+			continue
+		}
+		d := v.Sub(ref)
+		if d < -w.Before || d > w.After {
+			continue
+		}
+		if d < 0 {
+			d = -d
+		}
+		if best < 0 || d < best {
+			res, best = v, d
+		}
+	}
+	if best < 0 {
+		return time.Time{}, errors.Data
+	}
+	return res, nil
+}
+
+// layout returns the time layout matching the format.
+func layout(f Format) (s string) {
+	switch {
+	case f&Date != 0:
+		s = dateFmt
+	case f&YearMonth != 0:
+		s = yearMonthFmt
+	case f&MonthDay != 0:
+		s = monthDayFmt
+	}
+	if f&Time != 0 {
+		s += timeFmt
+	}
+	return
+}
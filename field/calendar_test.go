@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func at(layout, value string) func() time.Time {
+	return func() time.Time {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+}
+
+func TestCalendar_Resolve(t *testing.T) {
+	var (
+		are   = is.New(t)
+		paris = time.FixedZone("CET", 3600)
+		dt    = []struct {
+			id    field.ID
+			in    string
+			now   string
+			local *time.Location
+			out   string
+			err   error
+		}{
+			// Settlement date received on the new year's day.
+			{id: 15, in: "1231", now: "2019-01-01T00:05:00Z", out: "2018-12-31T00:00:00Z"},
+			{id: 15, in: "0102", now: "2018-12-31T23:55:00Z", out: "2019-01-02T00:00:00Z"},
+			// Transmission date built by a terminal with a clock in advance.
+			{id: 7, in: "0101000010", now: "2018-12-31T23:59:50Z", out: "2019-01-01T00:00:10Z"},
+			{id: 7, in: "1231235950", now: "2019-01-01T00:00:10Z", out: "2018-12-31T23:59:50Z"},
+			{id: 7, in: "0615120000", now: "2019-01-01T00:00:00Z", out: "2018-06-15T12:00:00Z"},
+			// Leap years.
+			{id: 17, in: "0229", now: "2020-03-02T10:00:00Z", out: "2020-02-29T00:00:00Z"},
+			{id: 17, in: "0229", now: "2019-03-02T10:00:00Z", err: errors.Data},
+			// Local transaction date and time.
+			{id: 13, in: "1231", now: "2019-01-01T00:30:00Z", local: paris, out: "2018-12-31T00:00:00+01:00"},
+			{id: 12, in: "013000", local: paris, out: "0000-01-01T01:30:00+01:00"},
+			// Dates with year.
+			{id: 14, in: "2212", out: "2022-12-01T00:00:00Z"},
+			{id: 73, in: "031128", out: "2003-11-28T00:00:00Z"},
+			// Not a date.
+			{id: 11, in: "000001", err: errors.Data},
+			{id: 13, err: errors.Data},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			d := field.New(tt.id)
+			if tt.in != "" {
+				d.Value = []byte(tt.in)
+			}
+			c := &field.Calendar{Local: tt.local}
+			if tt.now != "" {
+				c.Now = at(time.RFC3339, tt.now)
+			}
+			out, err := c.Resolve(d)
+			are.Equal(err, tt.err)
+			if tt.err == nil {
+				are.Equal(out.Format(time.RFC3339), tt.out)
+			}
+		})
+	}
+}
+
+func TestCalendar_Compose(t *testing.T) {
+	var (
+		are   = is.New(t)
+		now   = at(time.RFC3339, "2018-12-31T23:30:00Z")()
+		paris = time.FixedZone("CET", 3600)
+		dt    = []struct {
+			id  field.ID
+			out string
+			err error
+		}{
+			{id: 7, out: "1231233000"},
+			{id: 12, out: "003000"},
+			{id: 13, out: "0101"},
+			{id: 14, out: "1812"},
+			{id: 15, out: "1231"},
+			{id: 73, out: "181231"},
+			{id: 11, err: errors.Data},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			var (
+				c = &field.Calendar{Local: paris, Now: func() time.Time { return now }}
+				d = field.New(tt.id)
+			)
+			err := c.Compose(d, now)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			are.Equal(d.String(), tt.out)
+			are.True(d.Valid())
+			// Round trip.
+			out, err := c.Resolve(d)
+			are.NoErr(err)
+			are.Equal(out.Format(layouts[tt.id]), now.In(out.Location()).Format(layouts[tt.id]))
+		})
+	}
+}
+
+var layouts = map[field.ID]string{
+	7:  "0102150405",
+	12: "150405",
+	13: "0102",
+	14: "0601",
+	15: "0102",
+	73: "060102",
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Strictness is the level of validation of the characters of a data element.
+type Strictness uint8
+
+// List of levels of validation.
+const (
+	// Lenient accepts the characters of the ISO 8583 classes of the format, as well as the lower case
+	// and accented letters as alphabetic, and any punctuation, symbol or blank as special.
+	// The track data also accept any letter, punctuation, symbol or blank, as written in the track 1.
+	// It is the default level, some hosts sending such characters.
+	Lenient Strictness = iota
+	// Strict only accepts the ASCII characters of the ISO 8583 classes of the format:
+	// upper case letters and blank for a, digits for n, printable characters other than letters
+	// and digits for s, digits and the separators '=', 'D' and '^' for z.
+	Strict
+	// Off does not check the characters, except for the binary data.
+	Off
+)
+
+var strictness = []string{"lenient", "strict", "off"}
+
+// String implements the fmt.Stringer interface.
+func (s Strictness) String() string {
+	if int(s) < len(strictness) {
+		return strictness[s]
+	}
+	return ""
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s Strictness) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *Strictness) UnmarshalText(text []byte) error {
+	for k, v := range strictness {
+		if strings.EqualFold(v, string(text)) {
+			*s = Strictness(k)
+			return nil
+		}
+	}
+	return errors.Data
+}
+
+// charset returns in success if all the characters of the value belong to the classes of the format,
+// with this level of strictness.
+// A value in the format x+n starts with 'C' for a credit or 'D' for a debit.
+// The binary data are written with the characters '0' and '1', whatever the strictness.
+func charset(f Format, s Strictness, v []byte) bool {
+	switch {
+	case f == Binary:
+		return are(v, isBinary)
+	case s == Off:
+		return true
+	case f&Amount != 0:
+		return len(v) > 1 && isAmount(rune(v[0])) && are(v[1:], class(f&^Amount, s))
+	default:
+		return are(v, class(f, s))
+	}
+}
+
+// class returns the function checking a character of the classes of the format.
+func class(f Format, s Strictness) func(r rune) bool {
+	if f&Track != 0 {
+		if s != Lenient {
+			return isTrack
+		}
+		return func(r rune) bool {
+			return isTrack(r) || unicode.IsLetter(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Zs, r)
+		}
+	}
+	if f&(Alpha|Numeric|Special) == 0 {
+		// Dates and times are numeric.
+		f |= Numeric
+	}
+	return func(r rune) bool {
+		switch {
+		case f&Numeric != 0 && isDigit(r):
+			return true
+		case f&Alpha != 0 && (isUpper(r) || r == ' '):
+			return true
+		case f&Special != 0 && isSpecial(r):
+			return true
+		case s != Lenient:
+			return false
+		case f&Alpha != 0 && unicode.IsLetter(r):
+			return true
+		default:
+			return f&Special != 0 && (unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Zs, r))
+		}
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+// isSpecial returns in success if the character is a printable ASCII character, neither letter nor digit.
+// It includes the blank.
+func isSpecial(r rune) bool {
+	return r >= ' ' && r <= '~' && !isDigit(r) && !isUpper(r) && (r < 'a' || r > 'z')
+}
+
+// isTrack returns in success if the character belongs to the track data:
+// digits and the separators of the fields.
+func isTrack(r rune) bool {
+	return isDigit(r) || r == '=' || r == 'D' || r == '^'
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestData_Valid(t *testing.T) {
+	const (
+		a   = field.Alpha
+		n   = field.Numeric
+		s   = field.Special
+		ans = a | n | s
+	)
+	for i, tt := range []struct {
+		format field.Format
+		level  field.Strictness
+		in     string
+		ok     bool
+	}{
+		{format: a, in: "EUR", ok: true},
+		{format: a, in: "EU R", ok: true},
+		{format: a, in: "Eur", ok: true},
+		{format: a, in: "Eur", level: field.Strict},
+		{format: a, in: "Éte", ok: true},
+		{format: a, in: "ÉTE", level: field.Strict},
+		{format: a, in: "EU1"},
+		{format: n, in: "0123", ok: true},
+		{format: n, in: "01 3"},
+		{format: n, in: "٣"},
+		{format: n | field.MonthDay, in: "0420", ok: true, level: field.Strict},
+		{format: s, in: "-/.@", ok: true, level: field.Strict},
+		{format: s, in: "-/A"},
+		{format: a | n, in: "A1 B2", ok: true, level: field.Strict},
+		{format: a | n, in: "A1-B2"},
+		{format: n | s, in: "4000-0000", ok: true, level: field.Strict},
+		{format: n | s, in: "4000A0000"},
+		{format: ans, in: "SHOP 12, RUE DE L'ETE/PARIS @FR.", ok: true, level: field.Strict},
+		{format: ans, in: "Café «Paris» €", ok: true},
+		{format: ans, in: "Café", level: field.Strict},
+		{format: ans, in: "TAB\t"},
+		{format: field.Track, in: "4000000000000002=2412101", ok: true, level: field.Strict},
+		{format: field.Track, in: "4000000000000002D2412^101", ok: true},
+		{format: field.Track, in: "4000000000000002d2412", ok: true},
+		{format: field.Track, in: "4000000000000002d2412", level: field.Strict},
+		{format: field.Track, in: "B4000000000000002^DOE/JOHN^2412101", ok: true},
+		{format: field.Track, in: "B4000000000000002^DOE/JOHN^2412101", level: field.Strict},
+		{format: field.Track, in: "4000000000000002=2412\t"},
+		{format: field.Amount | n, in: "C00000150", ok: true, level: field.Strict},
+		{format: field.Amount | n, in: "D00000150", ok: true},
+		{format: field.Amount | n, in: "X00000150"},
+		{format: field.Amount | n, in: "C0000015O"},
+		{format: field.Amount | n, in: "C"},
+		{format: field.Binary, in: "01100001", ok: true},
+		{format: field.Binary, in: "01100002", level: field.Off},
+		{format: ans, in: "Café\t", ok: true, level: field.Off},
+		{format: field.Amount | n, in: "X", ok: true, level: field.Off},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			d := &field.Data{
+				Element: field.Element{Format: tt.format, Size: len(tt.in), Strictness: tt.level},
+				Value:   []byte(tt.in),
+			}
+			is.New(t).Equal(d.Valid(), tt.ok)
+		})
+	}
+}
+
+func TestStrictness_UnmarshalText(t *testing.T) {
+	var (
+		are  = is.New(t)
+		spec field.Spec
+	)
+	err := json.Unmarshal([]byte(`{"48": {"type": "llvar", "format": "ans", "size": 99, "strictness": "off"}}`), &spec)
+	are.NoErr(err)
+	are.Equal(spec.Element(48).Strictness, field.Off)
+	are.Equal(spec.Element(43).Strictness, field.Lenient)
+
+	e := spec.Element(49)
+	e.Strictness = field.Strict
+	b, err := json.Marshal(field.Spec{49: e})
+	are.NoErr(err)
+	are.Equal(string(b), `{"49":{"type":"fixed","format":"a","size":3,"strictness":"strict"}}`)
+
+	var l field.Strictness
+	are.True(l.UnmarshalText([]byte("unknown")) != nil)
+}
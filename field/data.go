@@ -5,50 +5,77 @@
 package field
 
 import (
+	"bytes"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
-	"unicode"
+	"unicode/utf8"
 
+	"github.com/rvflash/iso8583/encoding"
 	"github.com/rvflash/iso8583/errors"
 )
 
-// Marshal returns the encoded value of v.
-// todo
+// Marshal returns the encoded value of v, prefixed by its length for the variable fields.
+// Binary data are encoded in hexadecimal.
 func Marshal(v *Data) ([]byte, error) {
 	if !v.Valid() {
 		return nil, errors.Data
 	}
-	switch v.Type {
-	case LVar, LLVar, LLLVar:
-		switch {
-		case v.Format == Numeric:
-			return []byte(fmt.Sprintf("%0*d", v.Size, v.Value)), nil
-		case v.Format&Amount != 0:
-			return []byte(fmt.Sprintf("%s%0*d", v.Value[1:], v.Size-1, v.Value[1:])), nil
-		default:
-			return []byte(fmt.Sprintf("%*v", v.Size, v.Value)), nil
+	value := v.Value
+	if v.Format == Binary {
+		b, err := v.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		value = bytes.ToUpper(encoding.X(b))
+	}
+	prefix := v.prefixSize()
+	if prefix == 0 {
+		if len(v.Value) != v.Size {
+			return nil, errors.Length
 		}
+		return value, nil
 	}
-	return v.Value, nil
+	if len(v.Value) > v.Size || len(value) >= int(math.Pow10(prefix)) {
+		return nil, errors.Length
+	}
+	return append([]byte(fmt.Sprintf("%0*d", prefix, len(value))), value...), nil
 }
 
 // Unmarshal parses the gives data and stores the result into the Field pointed.
 func Unmarshal(data []byte, d *Data) (err error) {
+	_, err = UnmarshalAppend(data, d, nil)
+	return err
+}
+
+// UnmarshalAppend is like Unmarshal but the value of a binary data is appended to buf,
+// that is returned extended. It allows to reuse its memory between messages.
+// The other values are not copied: they share the memory of data.
+func UnmarshalAppend(data []byte, d *Data, buf []byte) (_ []byte, err error) {
 	d.Size, err = d.FixedSize(data)
 	if err != nil {
-		return err
+		return buf, err
 	}
 	if len(data) < d.Size {
-		return errors.OutOfRange
+		return buf, errors.OutOfRange
 	}
 	d.Value = data[d.prefixSize():d.Size]
 	d.Size -= d.prefixSize()
 
+	if d.Format == Binary {
+		v, err := encoding.ASCII.AppendBinary(buf, d.Value)
+		if err != nil {
+			return buf, errors.Data
+		}
+		d.Value = v[len(buf):len(v):len(v)]
+		buf = v
+		d.Size = len(d.Value)
+	}
 	if !d.Valid() {
-		return errors.Data
+		return buf, errors.Data
 	}
-	return nil
+	return buf, nil
 }
 
 // New returns a new instance of Field.
@@ -66,26 +93,58 @@ type Data struct {
 	Value []byte
 }
 
+// Bytes returns the value of a binary data as bytes, eight bits by byte.
+func (d *Data) Bytes() ([]byte, error) {
+	if d.Format != Binary || len(d.Value)%8 != 0 || !d.Valid() {
+		return nil, errors.Data
+	}
+	b := make([]byte, len(d.Value)/8)
+	for k, v := range d.Value {
+		b[k/8] = b[k/8]<<1 | (v - '0')
+	}
+	return b, nil
+}
+
+// SetBytes sets the value of a binary data with these bytes.
+func (d *Data) SetBytes(b []byte) error {
+	if d.Format != Binary {
+		return errors.Data
+	}
+	if d.Type == Fixed && d.Size > 0 && len(b)*8 != d.Size {
+		return errors.Length
+	}
+	d.Value = encoding.Binary(b)
+	d.Size = len(d.Value)
+
+	return nil
+}
+
 // FixedSize implements the Field interface.
+// For a variable data, the length given by the prefix can not exceed the size of the data element.
 func (d *Data) FixedSize(raw []byte) (int, error) {
 	prefix := d.prefixSize()
 	switch {
+	case prefix == 0 && d.Format == Binary:
+		// Binary data are encoded in hexadecimal: 4 bits by character.
+		return d.Size / 4, nil
 	case prefix == 0:
 		return d.Size, nil
 	case len(raw) < prefix:
 		return 0, errors.OutOfRange
 	default:
 		// Extracts the prefix header showing the length of the field.
-		i, err := strconv.Atoi(string(raw[:prefix]))
-		if err != nil {
-			return 0, err
+		var i int
+		for _, c := range raw[:prefix] {
+			if c < '0' || c > '9' {
+				return 0, errors.Length
+			}
+			i = i*10 + int(c-'0')
 		}
-		// Adds to it the prefix length.
-		i = i + prefix
-		if i == 0 {
+		if d.Format == Binary && i*4 > d.Size || d.Format != Binary && i > d.Size {
 			return 0, errors.Length
 		}
-		return i, nil
+		// Adds to it the prefix length.
+		return i + prefix, nil
 	}
 }
 
@@ -137,80 +196,39 @@ func (d *Data) String() string {
 	return string(d.Value)
 }
 
-// List of supported date formats
-const (
-	timeFmt      = "150405"
-	monthDayFmt  = "0102"
-	yearFmt      = "06"
-	yearMonthFmt = yearFmt + "01"
-	dateFmt      = yearFmt + monthDayFmt
-)
-
 // Time implements the Field interface.
+// Dates without year are resolved around the current time, see Calendar.
 func (d *Data) Time() (time.Time, error) {
-	if d.Value == nil || d.Size == 0 {
-		return time.Time{}, errors.Data
-	}
-	switch {
-	case d.Format&MonthDay != 0:
-		var (
-			layout = yearFmt + monthDayFmt
-			value  = time.Now().UTC().Format(yearFmt) + d.String()
-		)
-		if d.Format&Time != 0 {
-			return time.Parse(layout+timeFmt, value)
-		}
-		return time.Parse(layout, value)
-	case d.Format&Date != 0:
-		return time.Parse(dateFmt, d.String())
-	case d.Format&Time != 0:
-		return time.Parse(timeFmt, d.String())
-	case d.Format&YearMonth != 0:
-		return time.Parse(yearMonthFmt, d.String())
-	default:
-		return time.Time{}, errors.Data
-	}
+	return new(Calendar).Resolve(d)
 }
 
 // Valid implements the Field interface.
+// The characters of the value are checked against the classes of its format, see Strictness.
 func (d *Data) Valid() bool {
 	if d.Value == nil || d.Size == 0 {
 		return false
 	}
-	switch d.Format {
-	case Alpha:
-		return are(string(d.Value), unicode.IsLetter, unicode.IsSpace)
-	case Alpha | Numeric:
-		return are(string(d.Value), unicode.IsLetter, unicode.IsSpace, unicode.IsNumber)
-	case Alpha | Numeric | Special, Track:
-		return are(string(d.Value), unicode.IsLetter, unicode.IsSpace, unicode.IsNumber, unicode.IsSymbol)
-	case Numeric | Amount:
-		if len(d.Value) < 2 {
+	return charset(d.Format, d.Strictness, d.Value)
+}
+
+func are(b []byte, fn ...func(r rune) bool) bool {
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if !is(r, fn) {
 			return false
 		}
-		return are(string(d.Value[:1]), isAmount) && are(string(d.Value[1:]), unicode.IsNumber)
-	case Binary:
-		return are(string(d.Value), isBinary)
-	default:
-		return are(string(d.Value), unicode.IsNumber)
+		b = b[n:]
 	}
+	return true
 }
 
-func are(s string, fn ...func(r rune) bool) bool {
-	var is = func(r rune) bool {
-		for _, f := range fn {
-			if f(r) {
-				return true
-			}
+func is(r rune, fn []func(r rune) bool) bool {
+	for _, f := range fn {
+		if f(r) {
+			return true
 		}
-		return false
 	}
-	for _, r := range s {
-		if !is(r) {
-			return false
-		}
-	}
-	return true
+	return false
 }
 
 // List of types of amount.
@@ -58,9 +58,11 @@ type Field interface {
 
 // Element represents an ISO 8583 data field
 type Element struct {
-	Type   Type
-	Format Format
-	Size   int
+	Type   Type   `json:"type"`
+	Format Format `json:"format"`
+	Size   int    `json:"size"`
+	// Strictness is the level of validation of the characters, lenient by default.
+	Strictness Strictness `json:"strictness,omitempty"`
 }
 
 // ID is the position of the field in the list of data elements.
@@ -131,7 +133,7 @@ var n = map[ID]Element{
 	61:  {Format: Alpha | Numeric | Special, Size: 999, Type: LLLVar}, // Reserved private
 	62:  {Format: Alpha | Numeric | Special, Size: 999, Type: LLLVar}, // Reserved private
 	63:  {Format: Alpha | Numeric | Special, Size: 999, Type: LLLVar}, // Reserved private
-	64:  {Format: Binary, Size: 16},                                   // Message authentication code (MAC)
+	64:  {Format: Binary, Size: 64},                                   // Message authentication code (MAC)
 	65:  {Format: Binary, Size: 1},                                    // Bitmap, extended
 	66:  {Format: Numeric, Size: 1},                                   // Settlement code
 	67:  {Format: Numeric, Size: 2},                                   // Extended payment code
@@ -0,0 +1,30 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field_test
+
+import (
+	"testing"
+
+	"github.com/rvflash/iso8583/field"
+)
+
+func FuzzUnmarshal(f *testing.F) {
+	f.Add([]byte("164000000000000002"), uint8(2))
+	f.Add([]byte("000001"), uint8(11))
+	f.Add([]byte("C0000150"), uint8(28))
+	f.Add([]byte("-5ABCDEFG"), uint8(44))
+	f.Add([]byte("0129F0206000000002500"), uint8(55))
+	f.Add([]byte("0123456789abcdef"), uint8(64))
+	f.Fuzz(func(t *testing.T, data []byte, id uint8) {
+		d := field.New(field.ID(id))
+		if err := field.Unmarshal(data, d); err != nil {
+			return
+		}
+		// A decoded data element can be encoded again.
+		if _, err := field.Marshal(d); err != nil {
+			t.Fatalf("%d %q: %s", id, data, err)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field
+
+import "strings"
+
+// MaskChar is the character replacing the sensitive values.
+const MaskChar = "*"
+
+// List of the data elements with sensitive values.
+var sensitive = map[ID]bool{
+	2:  true, // Primary account number
+	14: true, // Date, expiration
+	34: true, // Primary account number, extended
+	35: true, // Track 2 data
+	36: true, // Track 3 data
+	45: true, // Track 1 data
+	52: true, // PIN data
+}
+
+// Sensitive returns true if the value of this data element must not be disclosed.
+func Sensitive(num ID) bool {
+	return sensitive[num]
+}
+
+// Mask returns the value of the field, masked if it is sensitive.
+func Mask(f Field) string {
+	return MaskValue(f.ID(), f.String())
+}
+
+// MaskValue returns the value of the data element, masked if it is sensitive.
+// Only the first six and the last four digits of an account number are kept, as allowed by PCI DSS.
+func MaskValue(num ID, s string) string {
+	if !Sensitive(num) {
+		return s
+	}
+	switch num {
+	case 2, 34:
+		if len(s) > 10 {
+			return s[:6] + strings.Repeat(MaskChar, len(s)-10) + s[len(s)-4:]
+		}
+	}
+	return strings.Repeat(MaskChar, len(s))
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Spec overrides the definition of some data elements.
+// The ISO 8583 definition is used for the others.
+type Spec map[ID]Element
+
+// New returns a new data element as defined by the specification.
+func (s Spec) New(num ID) *Data {
+	return &Data{
+		Element: s.Element(num),
+		Pos:     num,
+	}
+}
+
+// Element returns the definition of the data element.
+func (s Spec) Element(num ID) Element {
+	if e, ok := s[num]; ok {
+		return e
+	}
+	return n[num]
+}
+
+// List of notations of the formats, as used in the specifications.
+var formats = []struct {
+	f Format
+	s string
+}{
+	{Amount, "x+"},
+	{Alpha, "a"},
+	{Numeric, "n"},
+	{Special, "s"},
+	{Binary, "b"},
+	{Track, "z"},
+	{Date, " YYMMDD"},
+	{YearMonth, " YYMM"},
+	{MonthDay, " MMDD"},
+	{Time, " hhmmss"},
+}
+
+// String implements the fmt.Stringer interface.
+// The format uses the ISO notation, like "ans" or "x+n", followed by its date layouts.
+func (f Format) String() string {
+	var s string
+	for _, v := range formats {
+		if f&v.f != 0 {
+			s += v.s
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (f *Format) UnmarshalText(text []byte) error {
+	var (
+		out Format
+		s   = string(text)
+	)
+	for _, v := range formats {
+		if v.s[0] == ' ' {
+			continue
+		}
+		if strings.HasPrefix(s, v.s) {
+			out |= v.f
+			s = s[len(v.s):]
+		}
+	}
+	for _, p := range strings.Fields(s) {
+		var ok bool
+		for _, v := range formats {
+			if v.s[0] == ' ' && v.s[1:] == p {
+				out |= v.f
+				ok = true
+			}
+		}
+		if !ok {
+			return errors.Data
+		}
+	}
+	if out == 0 {
+		return errors.Data
+	}
+	*f = out
+	return nil
+}
+
+var types = []string{"fixed", "lvar", "llvar", "lllvar"}
+
+// String implements the fmt.Stringer interface.
+func (t Type) String() string {
+	if int(t) < len(types) {
+		return types[t]
+	}
+	return ""
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *Type) UnmarshalText(text []byte) error {
+	for k, v := range types {
+		if strings.EqualFold(v, string(text)) {
+			*t = Type(k)
+			return nil
+		}
+	}
+	return errors.Data
+}
+
+// String implements the fmt.Stringer interface.
+// The element uses the ISO notation, like "n 6", "ans..25" or "b...999".
+func (e Element) String() string {
+	var s string
+	if f := strings.Fields(e.Format.String()); len(f) > 0 {
+		s = f[0]
+	}
+	if e.Type == Fixed {
+		return s + " " + strconv.Itoa(e.Size)
+	}
+	return s + strings.Repeat(".", int(e.Type)) + strconv.Itoa(e.Size)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestFormat_UnmarshalText(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			in  string
+			out field.Format
+			err error
+		}{
+			{in: "ans", out: field.Alpha | field.Numeric | field.Special},
+			{in: "x+n", out: field.Amount | field.Numeric},
+			{in: "b", out: field.Binary},
+			{in: "n MMDD hhmmss", out: field.Numeric | field.MonthDay | field.Time},
+			{in: "n MMD", err: errors.Data},
+			{in: "", err: errors.Data},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			var out field.Format
+			err := out.UnmarshalText([]byte(tt.in))
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+			if tt.err == nil {
+				are.Equal(out.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestSpec_New(t *testing.T) {
+	var (
+		are  = is.New(t)
+		spec field.Spec
+	)
+	err := json.Unmarshal([]byte(`{"48": {"type": "LLVAR", "format": "ans", "size": 99}}`), &spec)
+	are.NoErr(err)
+	f := spec.New(48)
+	are.Equal(f.ID(), field.ID(48))
+	are.Equal(f.Element, field.Element{Type: field.LLVar, Format: field.Alpha | field.Numeric | field.Special, Size: 99})
+	are.Equal(spec.Element(11), field.New(11).Element)
+	are.Equal(field.Spec(nil).Element(11), field.New(11).Element)
+
+	b, err := json.Marshal(spec)
+	are.NoErr(err)
+	are.Equal(string(b), `{"48":{"type":"llvar","format":"ans","size":99}}`)
+}
+
+func TestMask(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			id      field.ID
+			in, out string
+		}{
+			{id: 2, in: "4012345678909", out: "401234***8909"},
+			{id: 2, in: "4012345678", out: "**********"},
+			{id: 35, in: "4012345678909=2512", out: "******************"},
+			{id: 11, in: "000001", out: "000001"},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			f := field.New(tt.id)
+			f.Value = []byte(tt.in)
+			are.Equal(field.Mask(f), tt.out)
+		})
+	}
+}
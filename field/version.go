@@ -0,0 +1,56 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package field
+
+// ISO1987 is the specification of ISO 8583:1987: the default definition of the data elements.
+var ISO1987 = Spec{}
+
+// ISO1993 lists the data elements of ISO 8583:1993 differing from ISO 8583:1987.
+var ISO1993 = Spec{
+	12: {Format: Numeric | Date | Time, Size: 12},                    // Date and time, local transaction (YYMMDDhhmmss)
+	13: {Format: Numeric | YearMonth, Size: 4},                       // Date, effective (YYMM)
+	15: {Format: Numeric | Date, Size: 6},                            // Date, settlement (YYMMDD)
+	22: {Format: Alpha | Numeric, Size: 12},                          // Point of service data code
+	24: {Format: Numeric, Size: 3},                                   // Function code
+	25: {Format: Numeric, Size: 4},                                   // Message reason code
+	26: {Format: Numeric, Size: 4},                                   // Card acceptor business code
+	28: {Format: Numeric | Date, Size: 6},                            // Date, reconciliation (YYMMDD)
+	29: {Format: Numeric, Size: 3},                                   // Reconciliation indicator
+	30: {Format: Numeric, Size: 24},                                  // Amounts, original
+	31: {Format: Alpha | Numeric | Special, Size: 99, Type: LLVar},   // Acquirer reference data
+	36: {Format: Track, Size: 104, Type: LLLVar},                     // Track 3 data
+	39: {Format: Numeric, Size: 3},                                   // Action code
+	40: {Format: Numeric, Size: 3},                                   // Service code
+	43: {Format: Alpha | Numeric | Special, Size: 99, Type: LLVar},   // Card acceptor name/location
+	44: {Format: Alpha | Numeric | Special, Size: 99, Type: LLVar},   // Additional response data
+	46: {Format: Alpha | Numeric | Special, Size: 204, Type: LLLVar}, // Amounts, fees
+	49: {Format: Alpha | Numeric, Size: 3},                           // Currency code, transaction
+	50: {Format: Alpha | Numeric, Size: 3},                           // Currency code, reconciliation
+	51: {Format: Alpha | Numeric, Size: 3},                           // Currency code, cardholder billing
+	56: {Format: Numeric, Size: 35, Type: LLVar},                     // Original data elements
+	57: {Format: Numeric, Size: 3},                                   // Authorization life cycle code
+	58: {Format: Numeric, Size: 11, Type: LLVar},                     // Authorizing agent institution identification code
+}
+
+// ISO2003 lists the data elements of ISO 8583:2003 differing from ISO 8583:1987.
+var ISO2003 = ISO1993.Extend(Spec{
+	39: {Format: Numeric, Size: 4}, // Action code
+	49: {Format: Numeric, Size: 3}, // Currency code, transaction
+	50: {Format: Numeric, Size: 3}, // Currency code, reconciliation
+	51: {Format: Numeric, Size: 3}, // Currency code, cardholder billing
+})
+
+// Extend returns a copy of the specification with these definitions, added or overridden.
+// It allows a dialect to define its own data elements on top of a version of the standard.
+func (s Spec) Extend(o Spec) Spec {
+	out := make(Spec, len(s)+len(o))
+	for k, v := range s {
+		out[k] = v
+	}
+	for k, v := range o {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package frame implements the length-prefix framing of the messages on a stream, like TCP.
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Prefix is the length prefix of a frame, excluding itself.
+type Prefix uint8
+
+// List of supported prefixes.
+const (
+	// Binary2 is a 2-byte unsigned integer in big endian.
+	Binary2 Prefix = iota
+	// Binary4 is a 4-byte unsigned integer in big endian.
+	Binary4
+	// ASCII4 is a number of 4 decimal digits.
+	ASCII4
+	// BCD2 is a number of 4 decimal digits packed in 2 bytes.
+	BCD2
+)
+
+// MaxLength is the maximum length of a frame to read, whatever its prefix.
+// It protects against a prefix announcing a huge frame and can be changed before reading.
+var MaxLength = 1 << 20
+
+var prefixes = []string{"2b", "4b", "4a", "2bcd"}
+
+// ParsePrefix returns the prefix behind its name: 2b, 4b, 4a or 2bcd.
+func ParsePrefix(s string) (Prefix, error) {
+	for k, v := range prefixes {
+		if strings.EqualFold(v, s) {
+			return Prefix(k), nil
+		}
+	}
+	return 0, errors.NotImplemented
+}
+
+// String implements the fmt.Stringer interface.
+func (p Prefix) String() string {
+	if int(p) < len(prefixes) {
+		return prefixes[p]
+	}
+	return ""
+}
+
+// Len returns the length of the prefix.
+func (p Prefix) Len() int {
+	switch p {
+	case Binary4, ASCII4:
+		return 4
+	default:
+		return 2
+	}
+}
+
+// Max returns the maximum length of a frame.
+// A 4-byte binary prefix is limited to the largest signed 32-bit integer, to fit in an int on any platform.
+func (p Prefix) Max() int {
+	switch p {
+	case Binary2:
+		return 1<<16 - 1
+	case Binary4:
+		return math.MaxInt32
+	default:
+		return 9999
+	}
+}
+
+// Encode returns the prefix of a frame of this length.
+func (p Prefix) Encode(n int) ([]byte, error) {
+	if n < 0 || n > p.Max() {
+		return nil, errors.Length
+	}
+	b := make([]byte, p.Len())
+	switch p {
+	case Binary2:
+		binary.BigEndian.PutUint16(b, uint16(n))
+	case Binary4:
+		binary.BigEndian.PutUint32(b, uint32(n))
+	case ASCII4:
+		copy(b, strconv.Itoa(n + 10000)[1:])
+	case BCD2:
+		s := strconv.Itoa(n + 10000)[1:]
+		b[0] = (s[0]-'0')<<4 | (s[1] - '0')
+		b[1] = (s[2]-'0')<<4 | (s[3] - '0')
+	default:
+		return nil, errors.NotImplemented
+	}
+	return b, nil
+}
+
+// Decode returns the length of the frame behind the prefix.
+func (p Prefix) Decode(b []byte) (int, error) {
+	if len(b) < p.Len() {
+		return 0, errors.OutOfRange
+	}
+	switch p {
+	case Binary2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	case Binary4:
+		n := binary.BigEndian.Uint32(b)
+		if n > math.MaxInt32 {
+			return 0, errors.Length
+		}
+		return int(n), nil
+	case ASCII4:
+		n, err := strconv.ParseUint(string(b[:4]), 10, 16)
+		if err != nil {
+			return 0, errors.Length
+		}
+		return int(n), nil
+	case BCD2:
+		var n int
+		for _, v := range b[:2] {
+			if v>>4 > 9 || v&0x0F > 9 {
+				return 0, errors.Length
+			}
+			n = n*100 + int(v>>4)*10 + int(v&0x0F)
+		}
+		return n, nil
+	default:
+		return 0, errors.NotImplemented
+	}
+}
+
+// Read reads one frame and returns its content, without the prefix.
+func (p Prefix) Read(r io.Reader) ([]byte, error) {
+	b := make([]byte, p.Len())
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	n, err := p.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxLength {
+		return nil, errors.Length
+	}
+	b = make([]byte, n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Write writes the data as one frame.
+func (p Prefix) Write(w io.Writer, data []byte) error {
+	b, err := p.Encode(len(data))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, data...))
+	return err
+}
+
+// Split is a bufio.SplitFunc returning the content of each frame of the stream.
+func (p Prefix) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) < p.Len() {
+		if atEOF && len(data) > 0 {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	n, err := p.Decode(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > MaxLength {
+		return 0, nil, errors.Length
+	}
+	if len(data) < p.Len()+n {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return p.Len() + n, data[p.Len() : p.Len()+n], nil
+}
+
+// NewScanner returns a scanner of the frames of the stream.
+func (p Prefix) NewScanner(r io.Reader) *bufio.Scanner {
+	n := p.Max()
+	if n > MaxLength {
+		n = MaxLength
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), p.Len()+n)
+	s.Split(p.Split)
+	return s
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package frame_test
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+)
+
+func TestPrefix_Encode(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			p   frame.Prefix
+			n   int
+			out []byte
+			err error
+		}{
+			{p: frame.Binary2, n: 300, out: []byte{0x01, 0x2C}},
+			{p: frame.Binary4, n: 300, out: []byte{0, 0, 0x01, 0x2C}},
+			{p: frame.ASCII4, n: 300, out: []byte("0300")},
+			{p: frame.BCD2, n: 300, out: []byte{0x03, 0x00}},
+			{p: frame.ASCII4, n: 10000, err: errors.Length},
+			{p: frame.Binary2, n: -1, err: errors.Length},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tt.p.Encode(tt.n)
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+			if tt.err == nil {
+				n, err := tt.p.Decode(out)
+				are.NoErr(err)
+				are.Equal(n, tt.n)
+			}
+		})
+	}
+}
+
+func TestPrefix_Read(t *testing.T) {
+	are := is.New(t)
+	for _, p := range []frame.Prefix{frame.Binary2, frame.Binary4, frame.ASCII4, frame.BCD2} {
+		var buf bytes.Buffer
+		are.NoErr(p.Write(&buf, []byte("0800")))
+		are.NoErr(p.Write(&buf, []byte("0810")))
+		stream := buf.Bytes()
+
+		b, err := p.Read(&buf)
+		are.NoErr(err)
+		are.Equal(string(b), "0800")
+
+		// Split of a stream.
+		s := p.NewScanner(io.MultiReader(bytes.NewReader(stream[:3]), bytes.NewReader(stream[3:])))
+		var out []string
+		for s.Scan() {
+			out = append(out, s.Text())
+		}
+		are.NoErr(s.Err())
+		are.Equal(out, []string{"0800", "0810"})
+
+		// Truncated.
+		_, err = p.Read(bytes.NewReader(stream[:p.Len()+2]))
+		are.Equal(err, io.ErrUnexpectedEOF)
+		s = p.NewScanner(bytes.NewReader(stream[:p.Len()+2]))
+		are.True(!s.Scan())
+		are.Equal(s.Err(), io.ErrUnexpectedEOF)
+	}
+	_, err := frame.ASCII4.Read(bytes.NewReader([]byte("08A0")))
+	are.Equal(err, errors.Length)
+	// A prefix announcing a huge frame is not trusted.
+	_, err = frame.Binary4.Read(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x30}))
+	are.Equal(err, errors.Length)
+	_, err = frame.Binary4.Decode([]byte{0x80, 0, 0, 0})
+	are.Equal(err, errors.Length)
+	s := frame.Binary4.NewScanner(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x30}))
+	are.True(!s.Scan())
+	are.Equal(s.Err(), errors.Length)
+	_, err = frame.ParsePrefix("3b")
+	are.Equal(err, errors.NotImplemented)
+	p, err := frame.ParsePrefix("2BCD")
+	are.NoErr(err)
+	are.Equal(p, frame.BCD2)
+}
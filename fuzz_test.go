@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+)
+
+// seeds returns the messages of the testdata.
+func seeds(f *testing.F) []*iso {
+	files, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	var out []*iso
+	for _, v := range files {
+		msg, err := message(strings.TrimSuffix(filepath.Base(v), ".json"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, v := range seeds(f) {
+		f.Add([]byte(v.Message), uint8(encoding.ASCII), v.Header)
+		f.Add(encoding.ASCIIToEBCDIC([]byte(v.Message)), uint8(encoding.EBCDIC), v.Header)
+	}
+	f.Add([]byte("0800"+strings.Repeat("8000000000000000", 2)+"2"), uint8(encoding.ASCII), false)
+	f.Add([]byte("0100"+"4000000000000000"+"-5"), uint8(encoding.ASCII), false)
+	f.Fuzz(func(t *testing.T, data []byte, format uint8, header bool) {
+		m := &iso8583.Message{Format: encoding.Format(format % 3), Header: header}
+		if err := iso8583.Unmarshal(data, m); err != nil {
+			return
+		}
+		// A decoded message can be encoded again.
+		if _, err := iso8583.Marshal(m); err != nil {
+			t.Fatalf("%q: %s", data, err)
+		}
+	})
+}
+
+func FuzzBitmap(f *testing.F) {
+	f.Add([]byte("2020000000000000"))
+	f.Add([]byte("A0200000000000000400000000000000"))
+	f.Add([]byte("A020000000000000" + "8000000000000000" + "4000000000000001"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var b iso8583.Bitmap
+		if err := b.UnmarshalHex(data); err != nil {
+			return
+		}
+		var c iso8583.Bitmap
+		if err := c.UnmarshalHex(b.MarshalHex()); err != nil || c != b {
+			t.Fatalf("%q: %s != %s", data, c, b)
+		}
+		bin, _ := b.MarshalBinary()
+		if err := c.UnmarshalBinary(bin); err != nil || c != b {
+			t.Fatalf("%q: %s != %s", data, c, b)
+		}
+		// The bits of the next bitmaps are set with the data elements.
+		var d iso8583.Bitmap
+		for _, id := range b.Fields() {
+			d.Set(id)
+		}
+		if !reflect.DeepEqual(d.Fields(), b.Fields()) || d.Len() > b.Len() {
+			t.Fatalf("%q: %s != %s", data, d, b)
+		}
+		for _, id := range b.Fields() {
+			d.Clear(id)
+		}
+		if d != (iso8583.Bitmap{}) {
+			t.Fatalf("%q: %s", data, d)
+		}
+	})
+}
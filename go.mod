@@ -1,6 +1,9 @@
 module github.com/rvflash/iso8583
 
+go 1.21
+
 require (
 	github.com/matryer/is v1.2.0
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	gopkg.in/yaml.v3 v3.0.1
 )
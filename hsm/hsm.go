@@ -0,0 +1,174 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package hsm defines the cryptographic operations delegated to a hardware security module (HSM).
+// Keys never leave the HSM in clear: they are handled as cryptograms under its local master key (LMK).
+//
+// Software is an in-memory implementation to unit-test the handlers offline,
+// before wiring them to a real device.
+package hsm
+
+import (
+	"crypto/aes"
+	"crypto/des"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/internal/des3"
+	"github.com/rvflash/iso8583/mac"
+	"github.com/rvflash/iso8583/pin"
+)
+
+// HSM is implemented by any hardware security module.
+type HSM interface {
+	// GenerateKey returns a new random key of this type, algorithm and length in bytes.
+	GenerateKey(t KeyType, a Algorithm, size int) (Key, error)
+	// ImportKey imports a key of this type enciphered under the zone master key.
+	// If not nil, the key check value must match.
+	ImportKey(zmk Key, t KeyType, a Algorithm, encrypted, kcv []byte) (Key, error)
+	// ExportKey returns the key enciphered under the zone master key, with its check value.
+	ExportKey(zmk, k Key) (encrypted, kcv []byte, err error)
+	// TranslatePIN deciphers the PIN block with the source PIN key
+	// and enciphers it again with the destination one, in the destination format.
+	TranslatePIN(src, dst Key, block []byte, from, to pin.Format, pan string) ([]byte, error)
+	// GenerateMAC returns the MAC of data computed with the key.
+	GenerateMAC(k Key, a MACAlgorithm, data []byte) ([]byte, error)
+	// VerifyMAC checks the MAC of data with the key.
+	VerifyMAC(k Key, a MACAlgorithm, data, sum []byte) error
+}
+
+// KeyType is the usage of a key.
+type KeyType uint8
+
+// List of key types.
+const (
+	// ZMK is a zone master key, protecting the keys exchanged with a partner.
+	ZMK KeyType = iota + 1
+	// ZPK is a zone PIN key, protecting the PIN blocks exchanged with a partner.
+	ZPK
+	// TPK is a terminal PIN key.
+	TPK
+	// ZAK is a zone authentication key, computing the MAC of the messages exchanged with a partner.
+	ZAK
+	// TAK is a terminal authentication key.
+	TAK
+)
+
+// Valid validates the KeyType.
+func (t KeyType) Valid() bool {
+	return t >= ZMK && t <= TAK
+}
+
+// String implements the fmt.Stringer interface.
+func (t KeyType) String() string {
+	switch t {
+	case ZMK:
+		return "ZMK"
+	case ZPK:
+		return "ZPK"
+	case TPK:
+		return "TPK"
+	case ZAK:
+		return "ZAK"
+	case TAK:
+		return "TAK"
+	default:
+		return ""
+	}
+}
+
+// Algorithm is the algorithm of a key.
+type Algorithm uint8
+
+// List of key algorithms.
+const (
+	// TDES is a Triple DES key.
+	TDES Algorithm = iota
+	// AES is an AES key.
+	AES
+)
+
+// Valid returns in success if the key length matches the algorithm.
+func (a Algorithm) Valid(size int) bool {
+	switch a {
+	case TDES:
+		return size == 16 || size == 24
+	case AES:
+		return size == 16 || size == 24 || size == 32
+	default:
+		return false
+	}
+}
+
+// MACAlgorithm is the algorithm used to compute a MAC.
+type MACAlgorithm uint8
+
+// List of MAC algorithms, see the mac package.
+const (
+	// ISO9797Alg1 is the CBC-MAC with Triple DES.
+	ISO9797Alg1 MACAlgorithm = iota
+	// ISO9797Alg3 is the Retail MAC, aka ANSI X9.19.
+	ISO9797Alg3
+	// CMAC is the CMAC with the algorithm of the key.
+	CMAC
+	// HMACSHA256 is the HMAC with SHA-256.
+	HMACSHA256
+)
+
+// algorithm returns the implementation of the MAC algorithm for a key with this algorithm.
+func (a MACAlgorithm) algorithm(k Algorithm) mac.Algorithm {
+	switch a {
+	case ISO9797Alg1:
+		if k == TDES {
+			return mac.ISO9797Alg1{}
+		}
+	case ISO9797Alg3:
+		if k == TDES {
+			return mac.ISO9797Alg3{}
+		}
+	case CMAC:
+		if k == TDES {
+			return mac.CMAC{NewCipher: des3.NewCipher}
+		}
+		return mac.CMAC{}
+	case HMACSHA256:
+		return mac.HMACSHA256{}
+	}
+	return nil
+}
+
+// Key is a key managed by the HSM.
+type Key struct {
+	Type      KeyType
+	Algorithm Algorithm
+	// Value is the key enciphered under the local master key of the HSM.
+	Value []byte
+	// KCV is the key check value.
+	KCV []byte
+}
+
+// KCVLen is the length in bytes of a key check value.
+const KCVLen = 3
+
+// KCV returns the check value of a clear key: the 3 left-most bytes of a block of zeros
+// enciphered with a Triple DES key, or of the CMAC of a block of zeros with an AES key.
+func KCV(a Algorithm, key []byte) ([]byte, error) {
+	switch a {
+	case TDES:
+		b, err := des3.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, des.BlockSize)
+		b.Encrypt(out, out)
+		return out[:KCVLen], nil
+	case AES:
+		out, err := mac.CMAC{}.Sum(key, make([]byte, aes.BlockSize))
+		if err != nil {
+			return nil, err
+		}
+		return out[:KCVLen], nil
+	default:
+		return nil, errors.NotImplemented
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package hsm
+
+import (
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/mac"
+)
+
+// Sign computes the MAC of the message with the HSM and sets it in the field 64 or 128, see mac.Signer.
+func Sign(h HSM, k Key, a MACAlgorithm, msg *iso8583.Message) error {
+	return mac.New(&algorithm{h: h, k: k, a: a}).Sign(msg, nil)
+}
+
+// Verify checks the MAC of the message with the HSM, see mac.Input for the authenticated data.
+// The MAC is verified by the HSM, so the key may only be allowed to verify.
+// It returns the errors.MAC error if the MAC is missing.
+func Verify(h HSM, k Key, a MACAlgorithm, msg *iso8583.Message) error {
+	id, data, err := mac.Input(msg)
+	if err != nil {
+		return err
+	}
+	f, ok := msg.Data[id].(*field.Data)
+	if !ok {
+		return errors.MAC
+	}
+	sum, err := f.Bytes()
+	if err != nil {
+		return errors.MAC
+	}
+	return h.VerifyMAC(k, a, data, sum)
+}
+
+// algorithm computes the MAC with a key managed by the HSM.
+type algorithm struct {
+	h HSM
+	k Key
+	a MACAlgorithm
+}
+
+// Sum implements the mac.Algorithm interface.
+func (a *algorithm) Sum(_, data []byte) ([]byte, error) {
+	return a.h.GenerateMAC(a.k, a.a, data)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package hsm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/internal/des3"
+	"github.com/rvflash/iso8583/pin"
+)
+
+// NewSoftware returns a new instance of Software using this local master key, an AES key.
+func NewSoftware(lmk []byte) (*Software, error) {
+	b, err := aes.NewCipher(lmk)
+	if err != nil {
+		return nil, errors.Key
+	}
+	gcm, err := cipher.NewGCM(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Software{lmk: gcm}, nil
+}
+
+// Software is an HSM implemented in memory.
+// The keys are enciphered under a local master key, itself kept in memory: it must only be used for tests.
+// The keys are exchanged enciphered under the zone master key in ECB mode.
+type Software struct {
+	lmk cipher.AEAD
+}
+
+// Load imports a clear key, as a key custodian would do.
+func (s *Software) Load(t KeyType, a Algorithm, clear []byte) (Key, error) {
+	if !t.Valid() || !a.Valid(len(clear)) {
+		return Key{}, errors.Key
+	}
+	kcv, err := KCV(a, clear)
+	if err != nil {
+		return Key{}, err
+	}
+	nonce := make([]byte, s.lmk.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{
+		Type:      t,
+		Algorithm: a,
+		Value:     s.lmk.Seal(nonce, nonce, clear, []byte{byte(t), byte(a)}),
+		KCV:       kcv,
+	}, nil
+}
+
+// GenerateKey implements the HSM interface.
+func (s *Software) GenerateKey(t KeyType, a Algorithm, size int) (Key, error) {
+	if !a.Valid(size) {
+		return Key{}, errors.Key
+	}
+	clear := make([]byte, size)
+	_, err := rand.Read(clear)
+	if err != nil {
+		return Key{}, err
+	}
+	if a == TDES {
+		parity(clear)
+	}
+	return s.Load(t, a, clear)
+}
+
+// ImportKey implements the HSM interface.
+func (s *Software) ImportKey(zmk Key, t KeyType, a Algorithm, encrypted, kcv []byte) (Key, error) {
+	b, err := s.block(zmk, ZMK)
+	if err != nil {
+		return Key{}, err
+	}
+	clear, err := des3.DecryptECB(b, encrypted)
+	if err != nil {
+		return Key{}, errors.Key
+	}
+	k, err := s.Load(t, a, clear)
+	if err != nil {
+		return Key{}, err
+	}
+	if kcv != nil && !hmac.Equal(kcv, k.KCV) {
+		return Key{}, errors.Key
+	}
+	return k, nil
+}
+
+// ExportKey implements the HSM interface.
+func (s *Software) ExportKey(zmk, k Key) (encrypted, kcv []byte, err error) {
+	b, err := s.block(zmk, ZMK)
+	if err != nil {
+		return nil, nil, err
+	}
+	clear, err := s.open(k, k.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	encrypted, err = des3.EncryptECB(b, clear)
+	if err != nil {
+		return nil, nil, errors.Key
+	}
+	return encrypted, k.KCV, nil
+}
+
+// TranslatePIN implements the HSM interface.
+func (s *Software) TranslatePIN(src, dst Key, block []byte, from, to pin.Format, pan string) ([]byte, error) {
+	in, err := s.pinKey(src, from)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.pinKey(dst, to)
+	if err != nil {
+		return nil, err
+	}
+	p, err := from.Decrypt(in, block, pan)
+	if err != nil {
+		return nil, err
+	}
+	return to.Encrypt(out, p, pan)
+}
+
+// GenerateMAC implements the HSM interface.
+func (s *Software) GenerateMAC(k Key, a MACAlgorithm, data []byte) ([]byte, error) {
+	clear, err := s.open(k, ZAK, TAK)
+	if err != nil {
+		return nil, err
+	}
+	alg := a.algorithm(k.Algorithm)
+	if alg == nil {
+		return nil, errors.NotImplemented
+	}
+	return alg.Sum(clear, data)
+}
+
+// VerifyMAC implements the HSM interface.
+// The MAC can be truncated: only its length is compared.
+func (s *Software) VerifyMAC(k Key, a MACAlgorithm, data, sum []byte) error {
+	out, err := s.GenerateMAC(k, a, data)
+	if err != nil {
+		return err
+	}
+	if len(sum) == 0 || len(sum) > len(out) || !hmac.Equal(out[:len(sum)], sum) {
+		return errors.MAC
+	}
+	return nil
+}
+
+// block returns the block cipher using this key, if it has one of these types.
+func (s *Software) block(k Key, types ...KeyType) (cipher.Block, error) {
+	clear, err := s.open(k, types...)
+	if err != nil {
+		return nil, err
+	}
+	if k.Algorithm == AES {
+		return aes.NewCipher(clear)
+	}
+	return des3.NewCipher(clear)
+}
+
+// open returns the clear key, if it has one of these types.
+func (s *Software) open(k Key, types ...KeyType) ([]byte, error) {
+	var ok bool
+	for _, t := range types {
+		ok = ok || k.Type == t
+	}
+	n := s.lmk.NonceSize()
+	if !ok || len(k.Value) < n {
+		return nil, errors.Key
+	}
+	clear, err := s.lmk.Open(nil, k.Value[:n], k.Value[n:], []byte{byte(k.Type), byte(k.Algorithm)})
+	if err != nil {
+		return nil, errors.Key
+	}
+	return clear, nil
+}
+
+// pinKey returns the clear PIN key, if its algorithm matches the PIN block format.
+func (s *Software) pinKey(k Key, f pin.Format) ([]byte, error) {
+	if (f == pin.ISO4) != (k.Algorithm == AES) {
+		return nil, errors.Key
+	}
+	return s.open(k, ZPK, TPK)
+}
+
+// parity sets the odd parity on each byte of a DES key.
+func parity(key []byte) {
+	for i, b := range key {
+		b &= 0xFE
+		n := b
+		n ^= n >> 4
+		n ^= n >> 2
+		n ^= n >> 1
+		if n&1 == 0 {
+			b |= 1
+		}
+		key[i] = b
+	}
+}
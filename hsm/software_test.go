@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package hsm_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/hsm"
+	"github.com/rvflash/iso8583/pin"
+)
+
+const pan = "4012345678909"
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newSoftware(t *testing.T) *hsm.Software {
+	s, err := hsm.NewSoftware(unhex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKCV(t *testing.T) {
+	are := is.New(t)
+	out, err := hsm.KCV(hsm.TDES, unhex("0123456789ABCDEFFEDCBA9876543210"))
+	are.NoErr(err)
+	are.Equal(strings.ToUpper(hex.EncodeToString(out)), "08D7B4")
+	out, err = hsm.KCV(hsm.AES, unhex("2b7e151628aed2a6abf7158809cf4f3c"))
+	are.NoErr(err)
+	are.Equal(len(out), hsm.KCVLen)
+	_, err = hsm.KCV(hsm.TDES, unhex("0123"))
+	are.Equal(err, errors.Key)
+}
+
+func TestSoftware_ImportKey(t *testing.T) {
+	var (
+		are      = is.New(t)
+		zmk      = unhex("0123456789ABCDEFFEDCBA9876543210")
+		acq, iss = newSoftware(t), newSoftware(t)
+	)
+	acqZMK, err := acq.Load(hsm.ZMK, hsm.TDES, zmk)
+	are.NoErr(err)
+	issZMK, err := iss.Load(hsm.ZMK, hsm.TDES, zmk)
+	are.NoErr(err)
+	// The issuer sends a new key to the acquirer.
+	zpk, err := iss.GenerateKey(hsm.ZPK, hsm.TDES, 16)
+	are.NoErr(err)
+	b, kcv, err := iss.ExportKey(issZMK, zpk)
+	are.NoErr(err)
+	out, err := acq.ImportKey(acqZMK, hsm.ZPK, hsm.TDES, b, kcv)
+	are.NoErr(err)
+	are.Equal(out.KCV, zpk.KCV)
+	are.Equal(out.Type, hsm.ZPK)
+	are.True(string(out.Value) != string(zpk.Value))
+
+	_, err = acq.ImportKey(acqZMK, hsm.ZPK, hsm.TDES, b, []byte{1, 2, 3})
+	are.Equal(err, errors.Key)
+	_, err = acq.ImportKey(zpk, hsm.ZPK, hsm.TDES, b, kcv)
+	are.Equal(err, errors.Key)
+}
+
+func TestSoftware_TranslatePIN(t *testing.T) {
+	var (
+		are    = is.New(t)
+		s      = newSoftware(t)
+		tpkKey = unhex("0123456789ABCDEFFEDCBA9876543210")
+		zpkKey = unhex("000102030405060708090a0b0c0d0e0f")
+	)
+	tpk, err := s.Load(hsm.TPK, hsm.TDES, tpkKey)
+	are.NoErr(err)
+	zpk, err := s.Load(hsm.ZPK, hsm.AES, zpkKey)
+	are.NoErr(err)
+	in, err := pin.ISO0.Encrypt(tpkKey, "1234", pan)
+	are.NoErr(err)
+	out, err := s.TranslatePIN(tpk, zpk, in, pin.ISO0, pin.ISO4, pan)
+	are.NoErr(err)
+	p, err := pin.ISO4.Decrypt(zpkKey, out, pan)
+	are.NoErr(err)
+	are.Equal(p, "1234")
+
+	// The format 4 requires an AES key.
+	_, err = s.TranslatePIN(tpk, zpk, in, pin.ISO0, pin.ISO0, pan)
+	are.Equal(err, errors.Key)
+	// A PIN key is required.
+	zak, err := s.Load(hsm.ZAK, hsm.TDES, tpkKey)
+	are.NoErr(err)
+	_, err = s.TranslatePIN(zak, zpk, in, pin.ISO0, pin.ISO4, pan)
+	are.Equal(err, errors.Key)
+}
+
+func TestSoftware_GenerateMAC(t *testing.T) {
+	var (
+		are  = is.New(t)
+		s    = newSoftware(t)
+		data = []byte("Now is the time for all ")
+	)
+	zak, err := s.Load(hsm.ZAK, hsm.TDES, unhex("0123456789abcdeffedcba9876543210"))
+	are.NoErr(err)
+	out, err := s.GenerateMAC(zak, hsm.ISO9797Alg3, data)
+	are.NoErr(err)
+	are.Equal(hex.EncodeToString(out), "a1c72e74ea3fa9b6")
+	are.NoErr(s.VerifyMAC(zak, hsm.ISO9797Alg3, data, out[:4]))
+	are.Equal(s.VerifyMAC(zak, hsm.ISO9797Alg3, data[1:], out), errors.MAC)
+
+	aes, err := s.GenerateKey(hsm.TAK, hsm.AES, 32)
+	are.NoErr(err)
+	_, err = s.GenerateMAC(aes, hsm.ISO9797Alg3, data)
+	are.Equal(err, errors.NotImplemented)
+	out, err = s.GenerateMAC(aes, hsm.CMAC, data)
+	are.NoErr(err)
+	are.Equal(len(out), 16)
+}
+
+func TestSign(t *testing.T) {
+	var (
+		are = is.New(t)
+		s   = newSoftware(t)
+		f11 = field.New(11)
+		msg = &iso8583.Message{
+			MTI:  iso8583.NewMTI(iso8583.V1987, iso8583.NetworkManagement),
+			Data: iso8583.Fields{11: f11},
+		}
+	)
+	f11.Value = []byte("000001")
+	tak, err := s.GenerateKey(hsm.TAK, hsm.TDES, 16)
+	are.NoErr(err)
+	are.NoErr(hsm.Sign(s, tak, hsm.ISO9797Alg3, msg))
+	_, ok := msg.Data[64]
+	are.True(ok)
+	are.NoErr(hsm.Verify(s, tak, hsm.ISO9797Alg3, msg))
+
+	other, err := s.GenerateKey(hsm.TAK, hsm.TDES, 16)
+	are.NoErr(err)
+	are.Equal(hsm.Verify(s, other, hsm.ISO9797Alg3, msg), errors.MAC)
+
+	// The key is only allowed to verify.
+	are.NoErr(hsm.Verify(verifyOnly{s}, tak, hsm.ISO9797Alg3, msg))
+	delete(msg.Data, 64)
+	are.Equal(hsm.Verify(s, tak, hsm.ISO9797Alg3, msg), errors.MAC)
+}
+
+// verifyOnly is an HSM refusing to generate a MAC.
+type verifyOnly struct {
+	*hsm.Software
+}
+
+func (verifyOnly) GenerateMAC(hsm.Key, hsm.MACAlgorithm, []byte) ([]byte, error) {
+	return nil, errors.Key
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package des3 provides the DES and Triple DES block ciphers for any key length used by the payment industry.
+package des3
+
+import (
+	"crypto/cipher"
+	"crypto/des"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// NewCipher returns a DES cipher.Block for a single length key (8 bytes),
+// or a Triple DES one for a double (16 bytes, K1 K2 K1) or triple length key (24 bytes).
+func NewCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 8:
+		return des.NewCipher(key)
+	case 16:
+		k := make([]byte, 0, 24)
+		k = append(k, key...)
+		k = append(k, key[:8]...)
+		return des.NewTripleDESCipher(k)
+	case 24:
+		return des.NewTripleDESCipher(key)
+	default:
+		return nil, errors.Key
+	}
+}
+
+// EncryptECB encrypts src with the block cipher in electronic codebook mode.
+// The length of src must be a multiple of the block size.
+func EncryptECB(b cipher.Block, src []byte) ([]byte, error) {
+	return ecb(b.BlockSize(), src, b.Encrypt)
+}
+
+// DecryptECB decrypts src with the block cipher in electronic codebook mode.
+// The length of src must be a multiple of the block size.
+func DecryptECB(b cipher.Block, src []byte) ([]byte, error) {
+	return ecb(b.BlockSize(), src, b.Decrypt)
+}
+
+func ecb(size int, src []byte, fn func(dst, src []byte)) ([]byte, error) {
+	if len(src) == 0 || len(src)%size != 0 {
+		return nil, errors.Length
+	}
+	dst := make([]byte, len(src))
+	for i := 0; i < len(src); i += size {
+		fn(dst[i:i+size], src[i:i+size])
+	}
+	return dst, nil
+}
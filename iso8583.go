@@ -8,13 +8,39 @@ package iso8583
 
 import "github.com/rvflash/iso8583/errors"
 
-// Marshal returns the iso 8683 encoding of Message.
-func Marshal(_ *Message) ([]byte, error) {
-	return nil, errors.NotImplemented
+// Marshal returns the iso 8583 encoding of Message.
+// The bitmap is built with the data elements of the message.
+func Marshal(m *Message) ([]byte, error) {
+	if m.MTI == nil || !m.MTI.Valid() {
+		return nil, errors.MTI
+	}
+	body, err := m.encode()
+	if err != nil {
+		return nil, err
+	}
+	if !m.Header {
+		return body, nil
+	}
+	head, err := m.Format.DecodeDecimal(uint64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+	return append(head, body...), nil
 }
 
 // Unmarshal parses the iso 8583-encoded data and stores the result in the Message pointed.
+// Except the binary ones, the values of the data elements share the memory of data,
+// which must not be modified while the message is used.
+// The data must end with the last data element of the bitmaps.
+// To decode a stream of messages without allocating, reuse the same Message, reset between each.
 func Unmarshal(data []byte, m *Message) error {
+	if m.mem == nil || !m.mem.reset {
+		m.mem = new(memory)
+	}
+	m.mem.reset = false
+	if n := m.maxLength(); n >= 0 && len(data) > n {
+		return errors.Length
+	}
 	// Parses the Header.
 	data, err := m.header(data)
 	if err != nil {
@@ -25,10 +51,18 @@ func Unmarshal(data []byte, m *Message) error {
 	if err != nil {
 		return err
 	}
-	// Parses all bitmaps.
-	data, err = m.bitmap(data)
+	// Parses all bitmaps, the rest is converted to ASCII.
+	bitmap, data, err := m.bitmap(data)
 	if err != nil {
-		return nil
+		return err
+	}
+	return m.fields(data, bitmap)
+}
+
+// maxLength returns the maximum length of a message to decode, negative if not limited.
+func (m *Message) maxLength() int {
+	if m.MaxLength == 0 {
+		return DefaultMaxLength
 	}
-	return m.fields(data, m.elements())
+	return m.MaxLength
 }
@@ -0,0 +1,200 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package isotest provides utilities to test the handling of ISO 8583 messages,
+// like a generator of random valid messages for any specification.
+package isotest
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/field"
+)
+
+// DefaultMaxLen is the default maximum length of the variable data.
+const DefaultMaxLen = 32
+
+// List of characters of the ISO 8583 classes, as checked by the strict validation.
+const (
+	digits   = "0123456789"
+	alpha    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ "
+	specials = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	track    = digits + "=D^"
+)
+
+// Generator creates random valid messages: each data element respects its definition,
+// its type, format and size, as well as its set of characters.
+// The zero value generates messages of any type with the built-in specification of their version.
+// A Generator is not safe for concurrent use.
+type Generator struct {
+	// Spec overrides the definition of some data elements.
+	// The others are defined by the built-in specification of the version of the MTI.
+	Spec field.Spec
+	// MTI is the type of the messages. If nil, a random valid type is used.
+	MTI *iso8583.MTI
+	// Fields lists the data elements that may be set, each one with a probability of one half.
+	// If empty, the positions of the three bitmaps are used:
+	// those that are not defined by the specification are ignored.
+	Fields []field.ID
+	// MaxLen caps the length of the variable data. If zero, DefaultMaxLen is used.
+	MaxLen int
+	// Rand is the source of the random values. If nil, a source seeded with the current time is used.
+	Rand *rand.Rand
+}
+
+// Message returns a new random message.
+func (g *Generator) Message() *iso8583.Message {
+	m := &iso8583.Message{
+		MTI:  g.MTI,
+		Spec: g.Spec,
+		Data: iso8583.Fields{},
+	}
+	if m.MTI == nil {
+		m.MTI = iso8583.NewMTI(
+			uint8(g.rand().Intn(iso8583.V2003+1)),
+			uint8(iso8583.Authorization+g.rand().Intn(iso8583.NetworkManagement)),
+			uint8(g.rand().Intn(iso8583.InstructionAcknowledgement+1)),
+			uint8(g.rand().Intn(iso8583.OtherRepeat+1)),
+		)
+	}
+	spec := m.Specification()
+	for _, id := range g.fields() {
+		if id == iso8583.Secondary || id == iso8583.Tertiary || g.rand().Intn(2) == 0 {
+			continue
+		}
+		if d := g.Data(spec, id); d != nil {
+			m.Data[id] = d
+		}
+	}
+	return m
+}
+
+// Data returns the data element at this position with a random valid value,
+// or nil if its definition does not allow any value.
+func (g *Generator) Data(spec field.Spec, id field.ID) *field.Data {
+	d := spec.New(id)
+	d.Value = g.Value(d.Element)
+	if d.Value == nil {
+		return nil
+	}
+	d.Size = len(d.Value)
+	return d
+}
+
+// Value returns a random valid value for the data element, or nil if its definition does not allow any value.
+// As expected by field.Data, a binary value is written with the characters '0' and '1'.
+func (g *Generator) Value(e field.Element) []byte {
+	n := g.length(e)
+	if n <= 0 {
+		return nil
+	}
+	switch {
+	case e.Format == field.Binary:
+		b := make([]byte, n)
+		g.rand().Read(b)
+		return encoding.Binary(b)
+	case e.Format&field.Track != 0:
+		return g.chars(track, n)
+	case e.Format&field.Amount != 0:
+		if n < 2 {
+			return nil
+		}
+		return append(g.chars("CD", 1), g.chars(charset(e.Format), n-1)...)
+	case e.Format&(field.Date|field.YearMonth|field.MonthDay|field.Time) != 0 && e.Type == field.Fixed:
+		// Dates and times are composed from a random time of this century.
+		d := &field.Data{Element: e}
+		t := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(g.rand().Int63n(int64(100 * 365 * 24 * time.Hour))))
+		if err := new(field.Calendar).Compose(d, t); err == nil {
+			return d.Value
+		}
+	}
+	return g.chars(charset(e.Format), n)
+}
+
+// length returns the number of characters of the value, or of bytes for a binary data.
+func (g *Generator) length(e field.Element) int {
+	max := e.Size
+	if e.Format == field.Binary {
+		if e.Type == field.Fixed && max%8 != 0 {
+			return 0
+		}
+		max /= 8
+	}
+	if e.Type == field.Fixed {
+		return max
+	}
+	// The length prefix limits the number of characters, binary data are written in hexadecimal.
+	limit := 1
+	for k := 0; k < int(e.Type); k++ {
+		limit *= 10
+	}
+	limit--
+	if e.Format == field.Binary {
+		limit /= 2
+	}
+	for _, v := range []int{limit, g.maxLen()} {
+		if v < max {
+			max = v
+		}
+	}
+	if max <= 0 {
+		return 0
+	}
+	return 1 + g.rand().Intn(max)
+}
+
+func (g *Generator) chars(set string, n int) []byte {
+	b := make([]byte, n)
+	for k := range b {
+		b[k] = set[g.rand().Intn(len(set))]
+	}
+	return b
+}
+
+// charset returns the characters of the classes of the format: a, n and s.
+func charset(f field.Format) string {
+	var s string
+	if f&field.Alpha != 0 {
+		s += alpha
+	}
+	if f&field.Numeric != 0 {
+		s += digits
+	}
+	if f&field.Special != 0 {
+		s += specials
+	}
+	if s == "" {
+		// Dates and times are numeric.
+		return digits
+	}
+	return s
+}
+
+func (g *Generator) fields() []field.ID {
+	if len(g.Fields) > 0 {
+		return g.Fields
+	}
+	list := make([]field.ID, 0, iso8583.MaxField-1)
+	for id := 2; id <= iso8583.MaxField; id++ {
+		list = append(list, field.ID(id))
+	}
+	return list
+}
+
+func (g *Generator) maxLen() int {
+	if g.MaxLen == 0 {
+		return DefaultMaxLen
+	}
+	return g.MaxLen
+}
+
+func (g *Generator) rand() *rand.Rand {
+	if g.Rand == nil {
+		g.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return g.Rand
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package isotest_test
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/isotest"
+)
+
+func TestGenerator_Message(t *testing.T) {
+	var (
+		are = is.New(t)
+		g   = &isotest.Generator{Rand: rand.New(rand.NewSource(1))}
+	)
+	for n := 0; n < 100; n++ {
+		m := g.Message()
+		are.True(m.MTI.Valid())
+		for id, f := range m.Data {
+			are.True(id != iso8583.Secondary && id != iso8583.Tertiary)
+			_, err := field.Marshal(f.(*field.Data))
+			are.NoErr(err)
+		}
+		_, err := iso8583.Marshal(m)
+		are.NoErr(err)
+	}
+	// Only the listed data elements of this type.
+	g = &isotest.Generator{MTI: iso8583.NewMTI(iso8583.V1993, iso8583.Financial), Fields: []field.ID{2, 3}}
+	for n := 0; n < 10; n++ {
+		m := g.Message()
+		are.Equal(m.Type(), "1200")
+		for id := range m.Data {
+			are.True(id == 2 || id == 3)
+		}
+	}
+}
+
+func TestGenerator_Value(t *testing.T) {
+	g := &isotest.Generator{Rand: rand.New(rand.NewSource(1)), MaxLen: 10}
+	for i, tt := range []struct {
+		in  field.Element
+		min int
+		max int
+	}{
+		{in: field.Element{Format: field.Numeric, Size: 6}, min: 6, max: 6},
+		{in: field.Element{Format: field.Alpha, Size: 3}, min: 3, max: 3},
+		{in: field.Element{Format: field.Numeric | field.Special, Size: 28, Type: field.LLVar}, min: 1, max: 10},
+		{in: field.Element{Format: field.Amount | field.Numeric, Size: 8}, min: 8, max: 8},
+		{in: field.Element{Format: field.Numeric | field.MonthDay | field.Time, Size: 10}, min: 10, max: 10},
+		{in: field.Element{Format: field.Track, Size: 37, Type: field.LLVar}, min: 1, max: 10},
+		{in: field.Element{Format: field.Alpha | field.Numeric | field.Special, Size: 5, Type: field.LLLVar}, min: 1, max: 5},
+		{in: field.Element{Format: field.Numeric, Size: 99, Type: field.LVar}, min: 1, max: 9},
+		{in: field.Element{Format: field.Binary, Size: 64}, min: 64, max: 64},
+		{in: field.Element{Format: field.Binary, Size: 64, Type: field.LLVar}, min: 8, max: 64},
+		{in: field.Element{Format: field.Binary, Size: 1}},
+		{in: field.Element{Format: field.Numeric}},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			are := is.New(t)
+			for n := 0; n < 20; n++ {
+				v := g.Value(tt.in)
+				if tt.max == 0 {
+					are.Equal(v, nil)
+					return
+				}
+				are.True(len(v) >= tt.min && len(v) <= tt.max)
+				// The values are valid, even with the strict validation of the characters.
+				e := tt.in
+				e.Strictness = field.Strict
+				are.True((&field.Data{Element: e, Value: v}).Valid())
+			}
+		})
+	}
+}
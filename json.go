@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+// jsonMessage is the JSON representation of a message, see Message.MarshalJSON.
+type jsonMessage struct {
+	Format string     `json:"encoding,omitempty"`
+	Header bool       `json:"header,omitempty"`
+	MTI    string     `json:"mti"`
+	Fields jsonFields `json:"fields,omitempty"`
+}
+
+// jsonFields are the fields by position.
+type jsonFields map[field.ID]*jsonField
+
+// MarshalJSON implements the json.Marshaler interface.
+// The fields are sorted by position.
+func (f jsonFields) MarshalJSON() ([]byte, error) {
+	ids := make([]int, 0, len(f))
+	for id := range f {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	buf := bytes.NewBufferString("{")
+	for k, id := range ids {
+		b, err := json.Marshal(f[field.ID(id)])
+		if err != nil {
+			return nil, err
+		}
+		if k > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + strconv.Itoa(id) + `":`)
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonField is the JSON representation of a field.
+type jsonField struct {
+	Value    string            `json:"value"`
+	Amount   *int64            `json:"amount,omitempty"`
+	Time     *time.Time        `json:"time,omitempty"`
+	Elements map[string]string `json:"elements,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (f *jsonField) MarshalJSON() ([]byte, error) {
+	if f.Amount == nil && f.Time == nil && f.Elements == nil {
+		return json.Marshal(f.Value)
+	}
+	type object jsonField
+	return json.Marshal((*object)(f))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (f *jsonField) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("\"")) {
+		return json.Unmarshal(b, &f.Value)
+	}
+	type object jsonField
+	return json.Unmarshal(b, (*object)(f))
+}
+
+// List of the data elements with an amount without sign.
+var amounts = map[field.ID]bool{
+	4: true, 5: true, 6: true, 8: true,
+	82: true, 83: true, 84: true, 85: true, 86: true, 87: true, 88: true, 89: true,
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The message is written as in the testdata:
+//
+//	{
+//	  "encoding": "ascii",
+//	  "header": true,
+//	  "mti": "0200",
+//	  "fields": {
+//	    "4": {"value": "000000001000", "amount": 1000},
+//	    "7": "0420090613",
+//	    "11": "000001",
+//	    "14": {"value": "2412", "time": "2024-12-01T00:00:00Z"},
+//	    "55": {"value": "9F0206000000001000", "elements": {"9F02": "000000001000"}}
+//	  }
+//	}
+//
+// The fields are sorted by position, except the bitmap (field 1) which is omitted.
+// A field is a string with its value, binary data in hexadecimal, or an object when it has a typed value:
+// "amount" in minor units, negative for a debit, "time" in RFC 3339 format for the dates with a year,
+// and "elements" for the sub-elements of a composite field by path, as in Diff.
+// The dates without year, like the field 7, only have their value: resolving their year depends on
+// the current time, see field.Calendar.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	return m.json(false)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Only the value of the fields is used, see MarshalJSON for the schema.
+// The specification of the message, if any, is used to define the data elements.
+func (m *Message) UnmarshalJSON(b []byte) error {
+	var (
+		src jsonMessage
+		err error
+	)
+	if err = json.Unmarshal(b, &src); err != nil {
+		return err
+	}
+	m.Format = encoding.ASCII
+	if src.Format != "" {
+		if m.Format, err = encoding.Parse(src.Format); err != nil {
+			return err
+		}
+	}
+	m.Header = src.Header
+	if m.MTI, err = ParseMTI(src.MTI); err != nil {
+		return err
+	}
+	m.Data = Fields{}
+	for id, v := range src.Fields {
+		if id <= 1 || v == nil {
+			continue
+		}
+		f := m.newData(id)
+		if f.Format == field.Binary {
+			b, err := hex.DecodeString(v.Value)
+			if err != nil {
+				return errors.New(errors.Data, int(id))
+			}
+			err = f.SetBytes(b)
+			if err != nil {
+				return errors.New(err, int(id))
+			}
+		} else {
+			f.Value = []byte(v.Value)
+			if f.Type != field.Fixed {
+				f.Size = len(f.Value)
+			}
+		}
+		if v.Value != "" && !f.Valid() {
+			return errors.New(errors.Data, int(id))
+		}
+		m.Data[id] = f
+	}
+	return nil
+}
+
+// Redacted is a message marshalled in JSON with its sensitive values masked,
+// including the sensitive EMV tags of the field 55. It can not be unmarshalled.
+type Redacted struct {
+	*Message
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	return r.json(true)
+}
+
+func (m *Message) json(redact bool) ([]byte, error) {
+	dst := jsonMessage{
+		Format: m.Format.String(),
+		Header: m.Header,
+		MTI:    m.Type(),
+		Fields: make(jsonFields, len(m.Data)),
+	}
+	for _, id := range m.ids() {
+		f := m.Data[field.ID(id)]
+		dst.Fields[f.ID()] = newJSONField(f, redact)
+	}
+	return json.Marshal(dst)
+}
+
+// text returns the value of the field as text, with the binary data in hexadecimal.
+// If redact is true, the sensitive values are masked.
+func text(f field.Field, redact bool) string {
+	d, ok := f.(*field.Data)
+	if !ok || d.Format != field.Binary {
+		if redact {
+			return field.Mask(f)
+		}
+		return f.String()
+	}
+	b, err := d.Bytes()
+	if err != nil {
+		return f.String()
+	}
+	s := strings.ToUpper(hex.EncodeToString(b))
+	if redact && field.Sensitive(f.ID()) {
+		return strings.Repeat(field.MaskChar, len(s))
+	}
+	return s
+}
+
+func newJSONField(f field.Field, redact bool) *jsonField {
+	var (
+		id  = f.ID()
+		out = &jsonField{Value: text(f, redact)}
+	)
+	if redact && field.Sensitive(id) {
+		return out
+	}
+	d, ok := f.(*field.Data)
+	if fn, ok := DefaultComposites[id]; ok {
+		if elements, err := fn(out.Value); err == nil && len(elements) > 0 {
+			out.Elements = elements
+		}
+	}
+	if redact && id == 55 {
+		for k, v := range out.Elements {
+			if p := strings.Split(k, "."); tlv.Sensitive(p[len(p)-1]) {
+				out.Elements[k] = strings.Repeat(field.MaskChar, len(v))
+				out.Value = strings.Repeat(field.MaskChar, len(out.Value))
+			}
+		}
+	}
+	if !ok {
+		return out
+	}
+	switch {
+	case d.Format&field.Amount != 0 && d.Valid():
+		n, err := strconv.ParseInt(d.String()[1:], 10, 64)
+		if err == nil && d.Value[0] == 'D' {
+			n = -n
+		}
+		if err == nil {
+			out.Amount = &n
+		}
+	case amounts[id] && d.Valid():
+		if n, err := strconv.ParseInt(d.String(), 10, 64); err == nil {
+			out.Amount = &n
+		}
+	case d.Format&(field.Date|field.YearMonth) != 0 && d.Format&field.MonthDay == 0:
+		if t, err := d.Time(); err == nil {
+			out.Time = &t
+		}
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestMessage_MarshalJSON(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = newMessage("0200", map[field.ID]string{
+			2:  "4012345678909",
+			4:  "000000001000",
+			7:  "0420090613",
+			28: "D00000050",
+			48: "0103abc",
+			55: "5A0840123456789099999F0206000000001000",
+			73: "190420",
+		})
+		f52 = field.New(52)
+	)
+	are.NoErr(f52.SetBytes([]byte{0x1B, 0x9C, 0x18, 0x45, 0xEB, 0x99, 0x3A, 0x7A}))
+	m.Data[52] = f52
+	m.Header = true
+
+	b, err := json.Marshal(m)
+	are.NoErr(err)
+	are.Equal(string(b), `{"encoding":"ascii","header":true,"mti":"0200","fields":{`+
+		`"2":"4012345678909",`+
+		`"4":{"value":"000000001000","amount":1000},`+
+		`"7":"0420090613",`+
+		`"28":{"value":"D00000050","amount":-50},`+
+		`"48":{"value":"0103abc","elements":{"01":"abc"}},`+
+		`"52":"1B9C1845EB993A7A",`+
+		`"55":{"value":"5A0840123456789099999F0206000000001000","elements":{"5A":"4012345678909999","9F02":"000000001000"}},`+
+		`"73":{"value":"190420","time":"2019-04-20T00:00:00Z"}}}`)
+
+	// Round trip.
+	out := new(iso8583.Message)
+	are.NoErr(json.Unmarshal(b, out))
+	are.Equal(out.Format, encoding.ASCII)
+	are.True(out.Header)
+	are.Equal(out.Type(), "0200")
+	are.Equal(len(iso8583.Diff(m, out)), 0)
+
+	// Redacted.
+	b, err = json.Marshal(iso8583.Redacted{Message: m})
+	are.NoErr(err)
+	are.Equal(string(b), `{"encoding":"ascii","header":true,"mti":"0200","fields":{`+
+		`"2":"401234***8909",`+
+		`"4":{"value":"000000001000","amount":1000},`+
+		`"7":"0420090613",`+
+		`"28":{"value":"D00000050","amount":-50},`+
+		`"48":{"value":"0103abc","elements":{"01":"abc"}},`+
+		`"52":"****************",`+
+		`"55":{"value":"**************************************","elements":{"5A":"****************","9F02":"000000001000"}},`+
+		`"73":{"value":"190420","time":"2019-04-20T00:00:00Z"}}}`)
+}
+
+func TestMessage_String(t *testing.T) {
+	are := is.New(t)
+	m := newMessage("0200", map[field.ID]string{11: "000001", 2: "4012345678909"})
+	are.Equal(m.String(), "MTI 0200\n002 401234***8909\n011 000001\n")
+}
+
+func TestMessage_UnmarshalJSON(t *testing.T) {
+	are := is.New(t)
+	// The shape of the testdata.
+	b, err := ioutil.ReadFile("testdata/ascii_network_management_request.json")
+	are.NoErr(err)
+	m := new(iso8583.Message)
+	are.NoErr(json.Unmarshal(b, m))
+	are.Equal(m.Format, encoding.ASCII)
+	are.Equal(m.Type(), "0800")
+	are.Equal(m.Data[70].String(), "001")
+	_, ok := m.Data[1]
+	are.True(!ok)
+	out, err := iso8583.Marshal(m)
+	are.NoErr(err)
+	are.Equal(string(out), "0800823A0000000000000400000000000000042009061390000109061304200420001")
+
+	// With a specification.
+	m = &iso8583.Message{Spec: field.Spec{48: {Type: field.LLVar, Format: field.Numeric, Size: 10}}}
+	err = json.Unmarshal([]byte(`{"mti": "0100", "fields": {"48": "0103abc"}}`), m)
+	are.Equal(err.(*errors.Field).Error(), "field #48: invalid data")
+	err = json.Unmarshal([]byte(`{"mti": "0100", "fields": {"52": {"value": "1B9C"}}}`), new(iso8583.Message))
+	are.Equal(err.(*errors.Field).Error(), "field #52: invalid length")
+	err = json.Unmarshal([]byte(`{"mti": "0100", "encoding": "utf8"}`), new(iso8583.Message))
+	are.Equal(err, errors.NotImplemented)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package keyexchange
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/hsm"
+)
+
+// Codec carries a key enciphered under the zone master key and its check value in a message.
+type Codec interface {
+	// Encode sets the key and its check value in the message.
+	Encode(msg *iso8583.Message, key, kcv []byte) error
+	// Decode returns the key and its check value carried by the message.
+	Decode(msg *iso8583.Message) (key, kcv []byte, err error)
+}
+
+// FieldCodec carries the key followed by its check value in one field, as defined by the specification
+// of the message: a private or a dialect field agreed with the partner, see Exchanger.Spec.
+// They are written in hexadecimal in a text field, as is in a binary field.
+type FieldCodec field.ID
+
+// Field48 uses the additional data, private (field 48).
+const Field48 = FieldCodec(48)
+
+// Encode implements the Codec interface.
+// The field is defined by the specification of the message.
+func (c FieldCodec) Encode(msg *iso8583.Message, key, kcv []byte) error {
+	var (
+		f   = &field.Data{Element: msg.Element(field.ID(c)), Pos: field.ID(c)}
+		err error
+	)
+	if f.Format == field.Binary {
+		err = f.SetBytes(append(append([]byte{}, key...), kcv...))
+	} else {
+		f.Value = bytes.ToUpper(encoding.X(append(append([]byte{}, key...), kcv...)))
+		if f.Type != field.Fixed {
+			f.Size = len(f.Value)
+		}
+		if !f.Valid() {
+			err = errors.Data
+		}
+	}
+	if err != nil {
+		return errors.New(err, int(c))
+	}
+	if msg.Data == nil {
+		msg.Data = iso8583.Fields{}
+	}
+	msg.Data[field.ID(c)] = f
+
+	return nil
+}
+
+// Decode implements the Codec interface.
+func (c FieldCodec) Decode(msg *iso8583.Message) (key, kcv []byte, err error) {
+	f, ok := msg.Data[field.ID(c)]
+	if !ok {
+		return nil, nil, errors.New(errors.Data, int(c))
+	}
+	var b []byte
+	if d, ok := f.(*field.Data); ok && d.Format == field.Binary {
+		b, err = d.Bytes()
+	} else {
+		b, err = hex.DecodeString(f.String())
+	}
+	if err != nil || len(b) <= hsm.KCVLen {
+		return nil, nil, errors.New(errors.Data, int(c))
+	}
+	n := len(b) - hsm.KCVLen
+	return b[:n], b[n:], nil
+}
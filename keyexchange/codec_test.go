@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package keyexchange_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/keyexchange"
+)
+
+// dialect defines the private field 120 as a binary data.
+var dialect = field.Spec{120: {Type: field.LLLVar, Format: field.Binary, Size: 2048}}
+
+func TestFieldCodec(t *testing.T) {
+	key, err := hex.DecodeString("A1B2C3D4E5F60718293A4B5C6D7E8F90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kcv := []byte{0xAB, 0xCD, 0xEF}
+	for i, tt := range []struct {
+		codec keyexchange.FieldCodec
+		spec  field.Spec
+		err   error
+	}{
+		{codec: keyexchange.Field48},
+		{codec: keyexchange.FieldCodec(120)},
+		{codec: keyexchange.FieldCodec(120), spec: dialect},
+		// The security related control information (n 16) can not hold a key.
+		{codec: keyexchange.FieldCodec(53), err: errors.New(errors.Data, 53)},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			are := is.New(t)
+			msg := &iso8583.Message{MTI: iso8583.NewMTI(iso8583.V1987, iso8583.NetworkManagement), Spec: tt.spec}
+			err := tt.codec.Encode(msg, key, kcv)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			b, err := iso8583.Marshal(msg)
+			are.NoErr(err)
+			out := &iso8583.Message{Spec: tt.spec}
+			are.NoErr(iso8583.Unmarshal(b, out))
+			k, v, err := tt.codec.Decode(out)
+			are.NoErr(err)
+			are.Equal(k, key)
+			are.Equal(v, kcv)
+		})
+	}
+}
+
+func TestExchanger_Codec(t *testing.T) {
+	var (
+		are      = is.New(t)
+		acq, iss = newExchanger(t), newExchanger(t)
+	)
+	for _, e := range []*keyexchange.Exchanger{acq, iss} {
+		e.Codec, e.Spec = keyexchange.FieldCodec(120), dialect
+	}
+	req, err := acq.Request(conn)
+	are.NoErr(err)
+	are.Equal(req.Data[120].(*field.Data).Format, field.Binary)
+	resp, err := iss.Handle(conn, wire(t, req))
+	are.NoErr(err)
+	are.Equal(resp.Data[39].String(), keyexchange.Approved)
+	are.NoErr(iss.Activate(conn))
+	are.NoErr(acq.HandleResponse(conn, wire(t, resp)))
+	a, ok := acq.Active(conn)
+	are.True(ok)
+	i, ok := iss.Active(conn)
+	are.True(ok)
+	are.Equal(a.KCV, i.KCV)
+}
@@ -0,0 +1,362 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package keyexchange rotates the working keys shared with a partner using network management messages:
+// an 0800 request with the network management information code (field 70) 101 or 161,
+// and its 0810 response.
+//
+// The new key is enciphered under the zone master key (ZMK) and sent with its check value.
+// All cryptographic operations are delegated to an hsm.HSM.
+// The key is carried in a private or dialect field agreed with the partner, see Exchanger.Codec and Exchanger.Spec.
+package keyexchange
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/hsm"
+)
+
+// List of network management information codes (field 70).
+const (
+	// KeyChange sends a new key to the partner.
+	KeyChange = "101"
+	// NewKey requests a new key to the partner.
+	NewKey = "161"
+)
+
+// List of response codes (field 39).
+const (
+	Approved          = "00"
+	FormatError       = "30"
+	SecurityViolation = "63"
+	SystemMalfunction = "96"
+)
+
+// List of the used data elements.
+const (
+	transmission field.ID = 7
+	stan         field.ID = 11
+	response     field.ID = 39
+	network      field.ID = 70
+)
+
+// Keys are the working keys of a connection.
+type Keys struct {
+	// Active is the key in use.
+	Active *hsm.Key
+	// Pending is the key sent to the partner, waiting for its approval,
+	// or the key received from the partner, waiting for the response to be sent.
+	Pending *hsm.Key
+
+	// trace and code are the trace number and the network management code of the request
+	// waiting for its response.
+	trace, code string
+}
+
+// Exchanger sends and answers the key exchange messages.
+// It stores the keys by connection and is safe for concurrent use.
+type Exchanger struct {
+	// HSM performs the cryptographic operations.
+	HSM hsm.HSM
+	// ZMK is the zone master key shared with the partner.
+	ZMK hsm.Key
+	// Type is the type of the exchanged key.
+	Type hsm.KeyType
+	// Algorithm and Size define the generated keys. By default, a double length TDES key.
+	Algorithm hsm.Algorithm
+	Size      int
+	// Codec carries the key in the messages. By default, the field 48.
+	Codec Codec
+	// Spec overrides the definition of some data elements of the messages, like the field of the Codec.
+	Spec field.Spec
+	// Now returns the current time. By default, time.Now.
+	Now func() time.Time
+
+	trace uint32
+	mu    sync.RWMutex
+	keys  map[string]Keys
+}
+
+// Keys returns the keys of the connection.
+func (e *Exchanger) Keys(conn string) Keys {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.keys[conn]
+}
+
+// Active returns the active key of the connection, if any.
+func (e *Exchanger) Active(conn string) (hsm.Key, bool) {
+	k := e.Keys(conn).Active
+	if k == nil {
+		return hsm.Key{}, false
+	}
+	return *k, true
+}
+
+// Activate replaces the active key of the connection by the pending one.
+func (e *Exchanger) Activate(conn string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	k := e.keys[conn]
+	if k.Pending == nil {
+		return errors.Key
+	}
+	k.Active, k.Pending = k.Pending, nil
+	e.keys[conn] = k
+
+	return nil
+}
+
+// Request returns a key change request for the connection, with a new key.
+// The key remains pending until the approval of the partner, see HandleResponse.
+func (e *Exchanger) Request(conn string) (*iso8583.Message, error) {
+	msg, err := e.request(KeyChange)
+	if err != nil {
+		return nil, err
+	}
+	k, err := e.send(msg)
+	if err != nil {
+		return nil, err
+	}
+	e.store(conn, func(keys *Keys) {
+		keys.Pending = &k
+		keys.trace, keys.code = msg.Data[stan].String(), KeyChange
+	})
+	return msg, nil
+}
+
+// RequestKey returns a request asking a new key to the partner for the connection.
+func (e *Exchanger) RequestKey(conn string) (*iso8583.Message, error) {
+	msg, err := e.request(NewKey)
+	if err != nil {
+		return nil, err
+	}
+	e.store(conn, func(keys *Keys) {
+		keys.trace, keys.code = msg.Data[stan].String(), NewKey
+	})
+	return msg, nil
+}
+
+// HandleResponse processes the response of the partner to the last request of the connection.
+// The response must echo the trace number (field 11) and the network management code (field 70)
+// of the request: a late or unexpected response is rejected without changing the keys.
+// The pending or received key becomes the active one if the partner has approved the exchange.
+func (e *Exchanger) HandleResponse(conn string, resp *iso8583.Message) error {
+	code, err := value(resp, network)
+	if err != nil {
+		return err
+	}
+	trace, err := value(resp, stan)
+	if err != nil {
+		return err
+	}
+	rc, err := value(resp, response)
+	if err != nil {
+		return err
+	}
+	if err = e.answer(conn, trace, code); err != nil {
+		return err
+	}
+	if rc != Approved {
+		e.store(conn, func(keys *Keys) {
+			keys.Pending = nil
+		})
+		return errors.New(errors.Declined, int(response))
+	}
+	switch code {
+	case KeyChange:
+		return e.Activate(conn)
+	case NewKey:
+		k, err := e.receive(resp)
+		if err != nil {
+			return err
+		}
+		e.store(conn, func(keys *Keys) {
+			keys.Active, keys.Pending = &k, nil
+		})
+		return nil
+	default:
+		return errors.New(errors.Data, int(network))
+	}
+}
+
+// Handle answers to the key exchange request of the partner for the connection:
+// it imports the received key or returns a new one.
+// This key remains pending until the response is sent to the partner, then Activate makes it active.
+// Errors are reported to the partner with the response code.
+func (e *Exchanger) Handle(conn string, req *iso8583.Message) (*iso8583.Message, error) {
+	resp, err := e.response(req)
+	if err != nil {
+		return nil, err
+	}
+	code, err := value(req, network)
+	if err != nil {
+		return resp, set(resp, response, FormatError)
+	}
+	var k hsm.Key
+	switch code {
+	case KeyChange:
+		k, err = e.receive(req)
+		if err != nil {
+			rc := FormatError
+			if err == errors.Key {
+				rc = SecurityViolation
+			}
+			return resp, set(resp, response, rc)
+		}
+	case NewKey:
+		k, err = e.send(resp)
+		if err != nil {
+			return resp, set(resp, response, SystemMalfunction)
+		}
+	default:
+		return resp, set(resp, response, FormatError)
+	}
+	e.store(conn, func(keys *Keys) {
+		keys.Pending = &k
+	})
+	return resp, set(resp, response, Approved)
+}
+
+// answer checks that the response matches the request of the connection waiting for it, then forgets the request.
+func (e *Exchanger) answer(conn, trace, code string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	k := e.keys[conn]
+	switch {
+	case k.trace == "" || k.trace != trace:
+		return errors.New(errors.Data, int(stan))
+	case k.code != code:
+		return errors.New(errors.Data, int(network))
+	}
+	k.trace, k.code = "", ""
+	e.keys[conn] = k
+	return nil
+}
+
+func (e *Exchanger) codec() Codec {
+	if e.Codec == nil {
+		return Field48
+	}
+	return e.Codec
+}
+
+func (e *Exchanger) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
+
+// receive imports the key carried by the message.
+func (e *Exchanger) receive(msg *iso8583.Message) (hsm.Key, error) {
+	b, kcv, err := e.codec().Decode(msg)
+	if err != nil {
+		return hsm.Key{}, err
+	}
+	return e.HSM.ImportKey(e.ZMK, e.Type, e.Algorithm, b, kcv)
+}
+
+// send generates a new key and sets it in the message.
+func (e *Exchanger) send(msg *iso8583.Message) (hsm.Key, error) {
+	size := e.Size
+	if size == 0 {
+		size = 16
+	}
+	k, err := e.HSM.GenerateKey(e.Type, e.Algorithm, size)
+	if err != nil {
+		return hsm.Key{}, err
+	}
+	b, kcv, err := e.HSM.ExportKey(e.ZMK, k)
+	if err != nil {
+		return hsm.Key{}, err
+	}
+	return k, e.codec().Encode(msg, b, kcv)
+}
+
+// store updates the keys of the connection.
+func (e *Exchanger) store(conn string, fn func(keys *Keys)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.keys == nil {
+		e.keys = make(map[string]Keys)
+	}
+	k := e.keys[conn]
+	fn(&k)
+	e.keys[conn] = k
+}
+
+// request returns a network management request with this code.
+func (e *Exchanger) request(code string) (*iso8583.Message, error) {
+	msg := &iso8583.Message{
+		MTI:  iso8583.NewMTI(iso8583.V1987, iso8583.NetworkManagement),
+		Spec: e.Spec,
+		Data: iso8583.Fields{},
+	}
+	f7 := field.New(transmission)
+	err := new(field.Calendar).Compose(f7, e.now())
+	if err != nil {
+		return nil, err
+	}
+	msg.Data[transmission] = f7
+	err = set(msg, stan, fmt.Sprintf("%06d", atomic.AddUint32(&e.trace, 1)%1e6))
+	if err != nil {
+		return nil, err
+	}
+	return msg, set(msg, network, code)
+}
+
+// response returns the response to this request, echoing its transmission date, trace number and code.
+func (e *Exchanger) response(req *iso8583.Message) (*iso8583.Message, error) {
+	if req.MTI == nil || req.MTI.Class != iso8583.NetworkManagement || req.MTI.Function != iso8583.Request {
+		return nil, errors.MTI
+	}
+	resp := &iso8583.Message{
+		MTI: &iso8583.MTI{
+			Version:  req.MTI.Version,
+			Class:    req.MTI.Class,
+			Function: iso8583.RequestResponse,
+			Origin:   req.MTI.Origin,
+		},
+		Format: req.Format,
+		Header: req.Header,
+		Spec:   e.Spec,
+		Data:   iso8583.Fields{},
+	}
+	for _, id := range []field.ID{transmission, stan, network} {
+		if f, ok := req.Data[id]; ok {
+			resp.Data[id] = f
+		}
+	}
+	return resp, nil
+}
+
+// set sets the value of the field in the message.
+func set(msg *iso8583.Message, id field.ID, v string) error {
+	f := field.New(id)
+	f.Value = []byte(v)
+	if f.Type != field.Fixed {
+		f.Size = len(v)
+	}
+	if !f.Valid() || (f.Type == field.Fixed && len(v) != f.Size) {
+		return errors.New(errors.Data, int(id))
+	}
+	msg.Data[id] = f
+	return nil
+}
+
+// value returns the value of the field in the message.
+func value(msg *iso8583.Message, id field.ID) (string, error) {
+	f, ok := msg.Data[id]
+	if !ok {
+		return "", errors.New(errors.Data, int(id))
+	}
+	return f.String(), nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package keyexchange_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/hsm"
+	"github.com/rvflash/iso8583/keyexchange"
+)
+
+const conn = "acquirer:1"
+
+func newExchanger(t *testing.T) *keyexchange.Exchanger {
+	lmk := make([]byte, 16)
+	s, err := hsm.NewSoftware(lmk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zmk, err := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := s.Load(hsm.ZMK, hsm.TDES, zmk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keyexchange.Exchanger{HSM: s, ZMK: k, Type: hsm.ZPK}
+}
+
+// wire sends the message over the network.
+func wire(t *testing.T, msg *iso8583.Message) *iso8583.Message {
+	b, err := iso8583.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &iso8583.Message{Spec: msg.Spec}
+	err = iso8583.Unmarshal(b, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestExchanger_Request(t *testing.T) {
+	var (
+		are      = is.New(t)
+		acq, iss = newExchanger(t), newExchanger(t)
+	)
+	req, err := acq.Request(conn)
+	are.NoErr(err)
+	are.Equal(req.Type(), "0800")
+	are.Equal(req.Data[70].String(), keyexchange.KeyChange)
+	pending := acq.Keys(conn).Pending
+	are.True(pending != nil)
+	_, ok := acq.Active(conn)
+	are.True(!ok)
+
+	resp, err := iss.Handle(conn, wire(t, req))
+	are.NoErr(err)
+	are.Equal(resp.Type(), "0810")
+	are.Equal(resp.Data[39].String(), keyexchange.Approved)
+	are.Equal(resp.Data[11].String(), req.Data[11].String())
+	// The received key is active once the response is sent.
+	_, ok = iss.Active(conn)
+	are.True(!ok)
+	are.NoErr(iss.Activate(conn))
+	k, ok := iss.Active(conn)
+	are.True(ok)
+	are.Equal(k.KCV, pending.KCV)
+
+	are.NoErr(acq.HandleResponse(conn, wire(t, resp)))
+	k, ok = acq.Active(conn)
+	are.True(ok)
+	are.Equal(k.KCV, pending.KCV)
+	are.True(acq.Keys(conn).Pending == nil)
+
+	// The response has already been handled.
+	err = acq.HandleResponse(conn, wire(t, resp))
+	are.Equal(err.Error(), "field #11: invalid data")
+}
+
+func TestExchanger_HandleResponse(t *testing.T) {
+	var (
+		are      = is.New(t)
+		acq, iss = newExchanger(t), newExchanger(t)
+	)
+	first, err := acq.Request(conn)
+	are.NoErr(err)
+	late, err := iss.Handle(conn, wire(t, first))
+	are.NoErr(err)
+	req, err := acq.Request(conn)
+	are.NoErr(err)
+	pending := acq.Keys(conn).Pending
+
+	// The response to the first request is too late.
+	err = acq.HandleResponse(conn, wire(t, late))
+	are.Equal(err.Error(), "field #11: invalid data")
+	are.Equal(acq.Keys(conn).Pending, pending)
+	_, ok := acq.Active(conn)
+	are.True(!ok)
+
+	// The response does not answer the request.
+	resp, err := iss.Handle(conn, wire(t, req))
+	are.NoErr(err)
+	f70 := field.New(70)
+	f70.Value = []byte(keyexchange.NewKey)
+	resp.Data[70] = f70
+	err = acq.HandleResponse(conn, wire(t, resp))
+	are.Equal(err.Error(), "field #70: invalid data")
+	are.Equal(acq.Keys(conn).Pending, pending)
+}
+
+func TestExchanger_RequestKey(t *testing.T) {
+	var (
+		are      = is.New(t)
+		acq, iss = newExchanger(t), newExchanger(t)
+	)
+	req, err := acq.RequestKey(conn)
+	are.NoErr(err)
+	are.Equal(req.Data[70].String(), keyexchange.NewKey)
+
+	resp, err := iss.Handle(conn, wire(t, req))
+	are.NoErr(err)
+	are.Equal(resp.Data[39].String(), keyexchange.Approved)
+	are.NoErr(iss.Activate(conn))
+	are.NoErr(acq.HandleResponse(conn, wire(t, resp)))
+
+	a, ok := acq.Active(conn)
+	are.True(ok)
+	i, ok := iss.Active(conn)
+	are.True(ok)
+	are.Equal(a.KCV, i.KCV)
+}
+
+func TestExchanger_Handle(t *testing.T) {
+	var (
+		are      = is.New(t)
+		acq, iss = newExchanger(t), newExchanger(t)
+	)
+	// Invalid check value.
+	req, err := acq.Request(conn)
+	are.NoErr(err)
+	key, _, err := keyexchange.Field48.Decode(req)
+	are.NoErr(err)
+	are.NoErr(keyexchange.Field48.Encode(req, key, []byte{0, 0, 0}))
+	resp, err := iss.Handle(conn, wire(t, req))
+	are.NoErr(err)
+	are.Equal(resp.Data[39].String(), keyexchange.SecurityViolation)
+	_, ok := iss.Active(conn)
+	are.True(!ok)
+
+	// The acquirer drops the pending key.
+	err = acq.HandleResponse(conn, wire(t, resp))
+	fe, ok := err.(*errors.Field)
+	are.True(ok)
+	are.Equal(fe.Error(), "field #39: declined")
+	are.True(acq.Keys(conn).Pending == nil)
+
+	// Missing key.
+	delete(req.Data, field.ID(keyexchange.Field48))
+	resp, err = iss.Handle(conn, req)
+	are.NoErr(err)
+	are.Equal(resp.Data[39].String(), keyexchange.FormatError)
+
+	// Not a network management request.
+	_, err = iss.Handle(conn, &iso8583.Message{MTI: iso8583.NewMTI(iso8583.V1987, iso8583.Financial)})
+	are.Equal(err, errors.MTI)
+}
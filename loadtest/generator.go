@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package loadtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// Default values of the generated requests.
+const (
+	DefaultBIN       = "400000"
+	DefaultPANLen    = 16
+	DefaultMCC       = "5411"
+	DefaultTerminal  = "TERM0001"
+	DefaultMerchant  = "MERCHANT0000001"
+	DefaultAcquirer  = "123456"
+	DefaultCurrency  = "EUR"
+	DefaultMaxAmount = 500000
+)
+
+// List of network management information codes (field 70).
+const (
+	signOn = "001"
+	echo   = "301"
+)
+
+// kinds lists the types of request generated.
+var kinds = map[string]bool{"0100": true, "0200": true, "0400": true, "0800": true}
+
+// Generator creates valid requests: authorizations (0100), financial requests (0200),
+// reversals (0400) and echo tests (0800), with incrementing STANs, random PANs passing the Luhn check,
+// amounts around a few tens and the current date and time.
+// It is safe for concurrent use. The zero value uses the default values.
+type Generator struct {
+	// BIN is the prefix of the PANs.
+	BIN string
+	// PANLen is the length of the PANs, check digit included.
+	PANLen int
+	// MCC is the merchant type (field 18).
+	MCC string
+	// Terminal is the card acceptor terminal identification (field 41).
+	Terminal string
+	// Merchant is the card acceptor identification code (field 42).
+	Merchant string
+	// Acquirer is the acquiring institution identification code (field 32).
+	Acquirer string
+	// Currency is the currency code of the transaction (field 49).
+	Currency string
+	// MaxAmount is the maximum amount in minor units.
+	MaxAmount int64
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+	// Rand is the source of the random values. If nil, a source seeded with the current time is used.
+	Rand *rand.Rand
+	// New returns an empty message, with its format and specification.
+	// By default, the messages are in ASCII without header.
+	New func() *iso8583.Message
+
+	mu   sync.Mutex
+	stan int
+}
+
+// Next returns a new request of this type: 0100, 0200, 0400 or 0800.
+func (g *Generator) Next(mti string) (*iso8583.Message, error) {
+	t, err := iso8583.ParseMTI(mti)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Rand == nil {
+		g.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	switch t.String() {
+	case "0100", "0200":
+		return g.request(t, "000000")
+	case "0400":
+		return g.reversal(t)
+	case "0800":
+		return g.network(t, echo)
+	default:
+		return nil, errors.NotImplemented
+	}
+}
+
+// SignOn returns a new sign-on request (0800).
+func (g *Generator) SignOn() (*iso8583.Message, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.network(iso8583.NewMTI(0, iso8583.NetworkManagement, iso8583.Request, 0), signOn)
+}
+
+// request returns an authorization or financial request.
+func (g *Generator) request(t *iso8583.MTI, code string) (*iso8583.Message, error) {
+	var (
+		m   = g.message(t)
+		now = g.now()
+	)
+	err := g.set(m, map[field.ID]string{
+		2:  g.pan(),
+		3:  code,
+		4:  fmt.Sprintf("%012d", g.amount()),
+		11: g.nextSTAN(),
+		18: or(g.MCC, DefaultMCC),
+		22: "051",
+		25: "00",
+		32: or(g.Acquirer, DefaultAcquirer),
+		41: or(g.Terminal, DefaultTerminal),
+		42: or(g.Merchant, DefaultMerchant),
+		49: or(g.Currency, DefaultCurrency),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = g.times(m, now, 7, 12, 13); err != nil {
+		return nil, err
+	}
+	if err = g.times(m, now.AddDate(2, 0, 0), 14); err != nil {
+		return nil, err
+	}
+	// The retrieval reference number is the julian date, the hour and the STAN.
+	rrn := fmt.Sprintf("%d%03d%02d%s", now.Year()%10, now.YearDay(), now.Hour(), m.Data[11].String())
+	return m, g.set(m, map[field.ID]string{37: rrn})
+}
+
+// reversal returns the reversal of a new financial request.
+func (g *Generator) reversal(t *iso8583.MTI) (*iso8583.Message, error) {
+	orig, err := g.request(iso8583.NewMTI(0, iso8583.Financial, iso8583.Request, 0), "000000")
+	if err != nil {
+		return nil, err
+	}
+	m := g.message(t)
+	for id, f := range orig.Data {
+		m.Data[id] = f
+	}
+	// The original data elements: MTI, STAN, transmission date and time and acquirer.
+	ode := orig.Type() + orig.Data[11].String() + orig.Data[7].String() + zeros(orig.Data[32].String(), 11) + zeros("", 11)
+	return m, g.set(m, map[field.ID]string{11: g.nextSTAN(), 90: ode})
+}
+
+// network returns a network management request.
+func (g *Generator) network(t *iso8583.MTI, code string) (*iso8583.Message, error) {
+	m := g.message(t)
+	if err := g.set(m, map[field.ID]string{11: g.nextSTAN(), 70: code}); err != nil {
+		return nil, err
+	}
+	return m, g.times(m, g.now(), 7)
+}
+
+// pan returns a random PAN starting with the BIN and ending with the Luhn check digit.
+func (g *Generator) pan() string {
+	var (
+		bin = or(g.BIN, DefaultBIN)
+		n   = g.PANLen
+	)
+	if n == 0 {
+		n = DefaultPANLen
+	}
+	b := []byte(bin)
+	for len(b) < n-1 {
+		b = append(b, byte('0'+g.Rand.Intn(10)))
+	}
+	return string(b) + strconv.Itoa(checkDigit(string(b)))
+}
+
+// amount returns a random amount following a log-normal distribution with a median of 25.00,
+// between 1 and the maximum amount.
+func (g *Generator) amount() int64 {
+	max := g.MaxAmount
+	if max == 0 {
+		max = DefaultMaxAmount
+	}
+	v := int64(math.Exp(g.Rand.NormFloat64() + math.Log(2500)))
+	switch {
+	case v < 1:
+		return 1
+	case v > max:
+		return max
+	default:
+		return v
+	}
+}
+
+// nextSTAN increments the system trace audit number, from 000001 to 999999.
+func (g *Generator) nextSTAN() string {
+	g.stan = g.stan%999999 + 1
+	return fmt.Sprintf("%06d", g.stan)
+}
+
+func (g *Generator) now() time.Time {
+	if g.Now == nil {
+		return time.Now()
+	}
+	return g.Now()
+}
+
+func (g *Generator) message(t *iso8583.MTI) *iso8583.Message {
+	m := new(iso8583.Message)
+	if g.New != nil {
+		m = g.New()
+	}
+	m.MTI = t
+	m.Data = iso8583.Fields{}
+	return m
+}
+
+func (g *Generator) set(m *iso8583.Message, values map[field.ID]string) error {
+	for id, v := range values {
+		f := &field.Data{Element: m.Element(id), Pos: id}
+		f.Value = []byte(v)
+		if f.Type != field.Fixed {
+			f.Size = len(f.Value)
+		}
+		if _, err := field.Marshal(f); err != nil {
+			return errors.New(err, int(id))
+		}
+		m.Data[id] = f
+	}
+	return nil
+}
+
+func (g *Generator) times(m *iso8583.Message, t time.Time, ids ...field.ID) error {
+	c := new(field.Calendar)
+	for _, id := range ids {
+		f := &field.Data{Element: m.Element(id), Pos: id}
+		if err := c.Compose(f, t); err != nil {
+			return errors.New(err, int(id))
+		}
+		m.Data[id] = f
+	}
+	return nil
+}
+
+// checkDigit returns the Luhn check digit of the number.
+func checkDigit(s string) int {
+	var sum int
+	for k := len(s) - 1; k >= 0; k-- {
+		d := int(s[k] - '0')
+		if (len(s)-k)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// zeros left pads the number with zeros.
+func zeros(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package loadtest_test
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/loadtest"
+	"github.com/rvflash/iso8583/simulator"
+)
+
+const scenario = `
+sign_on: true
+rules:
+  - match:
+      pan: ["5*"]
+    drop: 1
+  - match:
+      amount: {min: 10000}
+    response: "51"
+`
+
+// luhn returns true if the number passes the Luhn check.
+func luhn(s string) bool {
+	var sum int
+	for k := len(s) - 1; k >= 0; k-- {
+		d := int(s[k] - '0')
+		if (len(s)-k)%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func TestGenerator_Next(t *testing.T) {
+	var (
+		now = time.Date(2019, 4, 20, 9, 6, 13, 0, time.UTC)
+		g   = &loadtest.Generator{
+			Now:  func() time.Time { return now },
+			Rand: rand.New(rand.NewSource(1)),
+		}
+	)
+	for i, tt := range []struct {
+		mti    string
+		stan   string
+		fields []int
+		err    error
+	}{
+		{mti: "0100", stan: "000001", fields: []int{2, 3, 4, 7, 11, 12, 13, 14, 18, 22, 25, 32, 37, 41, 42, 49}},
+		{mti: "0200", stan: "000002", fields: []int{2, 3, 4, 7, 11, 12, 13, 14, 18, 22, 25, 32, 37, 41, 42, 49}},
+		{mti: "0400", stan: "000004", fields: []int{2, 3, 4, 7, 11, 12, 13, 14, 18, 22, 25, 32, 37, 41, 42, 49, 90}},
+		{mti: "0800", stan: "000005", fields: []int{7, 11, 70}},
+		{mti: "0300", err: errors.NotImplemented},
+		{mti: "ABCD", err: errors.MTI},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			are := is.New(t)
+			m, err := g.Next(tt.mti)
+			are.Equal(err, tt.err)
+			if err != nil {
+				return
+			}
+			are.Equal(m.Type(), tt.mti)
+			are.Equal(m.Data[11].String(), tt.stan)
+			are.Equal(len(m.Data), len(tt.fields))
+			for _, id := range tt.fields {
+				_, ok := m.Data[field.ID(id)]
+				are.True(ok)
+			}
+			if f, ok := m.Data[2]; ok {
+				are.True(strings.HasPrefix(f.String(), loadtest.DefaultBIN))
+				are.True(luhn(f.String()))
+			}
+			if f, ok := m.Data[7]; ok {
+				are.Equal(f.String(), "0420090613")
+			}
+			// The request is valid.
+			b, err := iso8583.Marshal(m)
+			are.NoErr(err)
+			are.NoErr(iso8583.Unmarshal(b, new(iso8583.Message)))
+		})
+	}
+}
+
+func TestHistogram_Percentile(t *testing.T) {
+	var (
+		are = is.New(t)
+		h   loadtest.Histogram
+	)
+	are.Equal(h.Percentile(50), time.Duration(0))
+	for k := 0; k < 90; k++ {
+		h.Add(3 * time.Millisecond)
+	}
+	for k := 0; k < 10; k++ {
+		h.Add(10 * time.Second)
+	}
+	are.Equal(h.Total, 100)
+	are.Equal(h.Min, 3*time.Millisecond)
+	are.Equal(h.Percentile(50), 5*time.Millisecond)
+	are.Equal(h.Percentile(90), 10*time.Second)
+	are.Equal(h.Percentile(99), 10*time.Second)
+	are.True(strings.Contains(h.String(), "<= 5ms"))
+}
+
+func TestRunner_Run(t *testing.T) {
+	are := is.New(t)
+	s, err := simulator.Load(strings.NewReader(scenario))
+	are.NoErr(err)
+	srv := &simulator.Server{Scenario: s}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	go func() { _ = srv.Serve(l) }()
+	defer func() { _ = srv.Close() }()
+
+	dial := func() (net.Conn, error) {
+		return net.Dial("tcp", l.Addr().String())
+	}
+	r := &loadtest.Runner{
+		Dial:     dial,
+		Prefix:   frame.Binary2,
+		Mix:      map[string]int{"0100": 2, "0200": 1, "0400": 1, "0800": 1},
+		Conns:    3,
+		TPS:      500,
+		Requests: 50,
+		Timeout:  time.Second,
+		SignOn:   true,
+	}
+	rep, err := r.Run(context.Background())
+	are.NoErr(err)
+	are.Equal(rep.Sent, 50)
+	are.Equal(rep.Timeouts, 0)
+	are.Equal(rep.Errors, 0)
+	are.Equal(rep.Codes["00"]+rep.Codes["51"], 50)
+	are.Equal(rep.Latency.Total, 50)
+	are.True(strings.Contains(rep.String(), "response code 00"))
+
+	// Without response, the requests time out.
+	r = &loadtest.Runner{
+		Dial:      dial,
+		Prefix:    frame.Binary2,
+		Generator: &loadtest.Generator{BIN: "51"},
+		Requests:  3,
+		Timeout:   50 * time.Millisecond,
+		SignOn:    true,
+	}
+	rep, err = r.Run(context.Background())
+	are.NoErr(err)
+	are.Equal(rep.Timeouts, 3)
+
+	// Unknown type of request.
+	r.Mix = map[string]int{"0300": 1}
+	_, err = r.Run(context.Background())
+	are.Equal(err, errors.NotImplemented)
+}
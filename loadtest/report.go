@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Buckets are the upper bounds of the latency histogram.
+var Buckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// Histogram counts the latencies by bucket. The last count is for the latencies above the last bucket.
+type Histogram struct {
+	Counts []int         `json:"counts"`
+	Total  int           `json:"total"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Sum    time.Duration `json:"sum"`
+}
+
+// Add adds a latency.
+func (h *Histogram) Add(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]int, len(Buckets)+1)
+	}
+	h.Counts[sort.Search(len(Buckets), func(i int) bool { return d <= Buckets[i] })]++
+	if h.Total == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Total++
+	h.Sum += d
+}
+
+// Mean returns the average latency.
+func (h *Histogram) Mean() time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Total)
+}
+
+// Percentile returns the upper bound of the bucket containing the p-th percentile, with p between 0 and 100.
+// Above the last bucket, the maximum latency is returned.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	var (
+		rank = int(p / 100 * float64(h.Total))
+		n    int
+	)
+	for k, c := range h.Counts {
+		n += c
+		if n > rank || n == h.Total {
+			if k == len(Buckets) {
+				return h.Max
+			}
+			return Buckets[k]
+		}
+	}
+	return h.Max
+}
+
+// String implements the fmt.Stringer interface.
+func (h *Histogram) String() string {
+	var (
+		buf strings.Builder
+		max int
+	)
+	for _, c := range h.Counts {
+		if c > max {
+			max = c
+		}
+	}
+	for k, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		label := "> " + Buckets[len(Buckets)-1].String()
+		if k < len(Buckets) {
+			label = "<= " + Buckets[k].String()
+		}
+		buf.WriteString(fmt.Sprintf("%8s %7d %s\n", label, c, strings.Repeat("#", c*40/max)))
+	}
+	return buf.String()
+}
+
+// Report summarizes a load test.
+type Report struct {
+	// Sent is the number of requests sent.
+	Sent int `json:"sent"`
+	// Codes counts the responses by response code (field 39).
+	Codes map[string]int `json:"codes"`
+	// Timeouts is the number of requests without response in time.
+	Timeouts int `json:"timeouts"`
+	// Errors is the number of requests failed for another reason, like an invalid response.
+	Errors int `json:"errors"`
+	// Latency is the histogram of the latencies of the responses.
+	Latency Histogram `json:"latency"`
+	// Elapsed is the duration of the test.
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// TPS returns the number of requests sent by second.
+func (r *Report) TPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Sent) / r.Elapsed.Seconds()
+}
+
+// String implements the fmt.Stringer interface.
+func (r *Report) String() string {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("requests: %d in %s (%.1f TPS)\n", r.Sent, r.Elapsed.Round(time.Millisecond), r.TPS()))
+	buf.WriteString(fmt.Sprintf("timeouts: %d (%s)\n", r.Timeouts, r.rate(r.Timeouts)))
+	buf.WriteString(fmt.Sprintf("errors: %d (%s)\n", r.Errors, r.rate(r.Errors)))
+	codes := make([]string, 0, len(r.Codes))
+	for c := range r.Codes {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	for _, c := range codes {
+		buf.WriteString(fmt.Sprintf("response code %s: %d (%s)\n", c, r.Codes[c], r.rate(r.Codes[c])))
+	}
+	h := &r.Latency
+	buf.WriteString(fmt.Sprintf(
+		"latency: min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		h.Min, h.Mean(), h.Percentile(50), h.Percentile(90), h.Percentile(99), h.Max,
+	))
+	buf.WriteString(h.String())
+	return buf.String()
+}
+
+func (r *Report) rate(n int) string {
+	if r.Sent == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)*100/float64(r.Sent))
+}
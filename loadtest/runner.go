@@ -0,0 +1,274 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package loadtest generates terminal traffic to load test an acquirer host or a switch.
+package loadtest
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+)
+
+// Runner sends generated requests on concurrent connections at a target rate,
+// one request at a time by connection, and reports the responses.
+type Runner struct {
+	// Dial opens a connection to the server.
+	Dial func() (net.Conn, error)
+	// Prefix is the length prefix of the frames.
+	Prefix frame.Prefix
+	// Generator creates the requests. If nil, a Generator with the default values is used.
+	Generator *Generator
+	// Mix is the weight of each type of request, like {"0100": 6, "0200": 3, "0400": 1}.
+	// By default, only authorizations are sent.
+	Mix map[string]int
+	// Conns is the number of concurrent connections. By default, one.
+	Conns int
+	// TPS is the target number of requests by second. Zero means as fast as possible.
+	TPS float64
+	// Requests is the number of requests to send. Zero means until the context is done.
+	Requests int
+	// Timeout is the maximum duration to wait for a response. Zero means no timeout.
+	// A connection with a request timed out is reopened.
+	Timeout time.Duration
+	// SignOn sends a sign-on on each connection before the requests.
+	SignOn bool
+}
+
+// Run sends the requests until the number of requests is reached or the context is done.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	mix, err := r.mix()
+	if err != nil {
+		return nil, err
+	}
+	g := r.Generator
+	if g == nil {
+		g = new(Generator)
+	}
+	n := r.Conns
+	if n <= 0 {
+		n = 1
+	}
+	conns := make([]net.Conn, n)
+	for k := range conns {
+		if conns[k], err = r.open(g); err != nil {
+			for _, c := range conns[:k] {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+	}
+	var (
+		rep   = &Report{Codes: make(map[string]int)}
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		next  int64
+		start = time.Now()
+		work  = r.pace(ctx)
+	)
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c net.Conn) {
+			defer wg.Done()
+			for range work {
+				mti := mix[int(atomic.AddInt64(&next, 1)-1)%len(mix)]
+				res := r.send(c, g, mti)
+				mu.Lock()
+				rep.add(res)
+				mu.Unlock()
+				if res.err != nil {
+					// The state of the connection is unknown.
+					if c != nil {
+						_ = c.Close()
+					}
+					c, _ = r.open(g)
+				}
+			}
+			if c != nil {
+				_ = c.Close()
+			}
+		}(c)
+	}
+	wg.Wait()
+	rep.Elapsed = time.Since(start)
+
+	return rep, nil
+}
+
+// result is the outcome of a request.
+type result struct {
+	code    string
+	latency time.Duration
+	timeout bool
+	err     error
+}
+
+func (r *Report) add(res result) {
+	r.Sent++
+	switch {
+	case res.timeout:
+		r.Timeouts++
+	case res.err != nil:
+		r.Errors++
+	default:
+		r.Codes[res.code]++
+		r.Latency.Add(res.latency)
+	}
+}
+
+// mix returns the sequence of the types of request, each one repeated by its weight and interleaved
+// with a smooth weighted round-robin.
+func (r *Runner) mix() ([]string, error) {
+	if len(r.Mix) == 0 {
+		return []string{"0100"}, nil
+	}
+	var (
+		types []string
+		total int
+	)
+	for mti, w := range r.Mix {
+		if !kinds[mti] {
+			return nil, errors.NotImplemented
+		}
+		if w < 0 {
+			return nil, errors.OutOfRange
+		}
+		types = append(types, mti)
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.OutOfRange
+	}
+	sort.Strings(types)
+	var (
+		out     = make([]string, total)
+		current = make([]int, len(types))
+	)
+	for k := range out {
+		best := 0
+		for i, mti := range types {
+			current[i] += r.Mix[mti]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		out[k] = types[best]
+	}
+	return out, nil
+}
+
+// pace returns a channel receiving a value for each request to send.
+// It is closed when all the requests have been sent or the context is done.
+func (r *Runner) pace(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{})
+	go func() {
+		defer close(c)
+		var tick <-chan time.Time
+		if r.TPS > 0 {
+			t := time.NewTicker(time.Duration(float64(time.Second) / r.TPS))
+			defer t.Stop()
+			tick = t.C
+		}
+		for k := 0; r.Requests == 0 || k < r.Requests; k++ {
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case c <- struct{}{}:
+			}
+		}
+	}()
+	return c
+}
+
+// open opens a connection and signs on if required.
+func (r *Runner) open(g *Generator) (net.Conn, error) {
+	c, err := r.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if !r.SignOn {
+		return c, nil
+	}
+	req, err := g.SignOn()
+	if err == nil {
+		err = r.exchange(c, req, nil)
+	}
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// send sends a new request on the connection and waits for its response.
+func (r *Runner) send(c net.Conn, g *Generator, mti string) (res result) {
+	if c == nil {
+		res.err = errors.Data
+		return
+	}
+	req, err := g.Next(mti)
+	if err != nil {
+		res.err = err
+		return
+	}
+	start := time.Now()
+	res.err = r.exchange(c, req, &res.code)
+	res.latency = time.Since(start)
+	if e, ok := res.err.(net.Error); ok && e.Timeout() {
+		res.timeout = true
+	}
+	return
+}
+
+// exchange sends the request and reads its response, with the same STAN.
+// The response code is stored in code, if not nil.
+func (r *Runner) exchange(c net.Conn, req *iso8583.Message, code *string) error {
+	b, err := iso8583.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if r.Timeout > 0 {
+		if err = c.SetDeadline(time.Now().Add(r.Timeout)); err != nil {
+			return err
+		}
+	}
+	if err = r.Prefix.Write(c, b); err != nil {
+		return err
+	}
+	if b, err = r.Prefix.Read(c); err != nil {
+		return err
+	}
+	res := &iso8583.Message{Format: req.Format, Header: req.Header, Spec: req.Spec}
+	if err = iso8583.Unmarshal(b, res); err != nil {
+		return err
+	}
+	stan, ok := res.Data[11]
+	if !ok || stan.String() != req.Data[11].String() {
+		return errors.New(errors.Data, 11)
+	}
+	if code == nil {
+		return nil
+	}
+	f, ok := res.Data[39]
+	if !ok {
+		return errors.New(errors.Data, 39)
+	}
+	*code = f.String()
+	return nil
+}
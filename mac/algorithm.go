@@ -0,0 +1,182 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package mac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/internal/des3"
+)
+
+// Padding is a padding method defined by ISO 9797-1.
+type Padding uint8
+
+// List of padding methods.
+const (
+	// Method1 pads with zeros, only if needed.
+	Method1 Padding = iota
+	// Method2 always adds a bit '1' followed by zeros.
+	Method2
+)
+
+// pad returns the data padded to a multiple of the block size.
+func (p Padding) pad(data []byte, size int) []byte {
+	n := len(data)
+	dst := make([]byte, n, n+size)
+	copy(dst, data)
+	if p == Method2 {
+		dst = append(dst, 0x80)
+	}
+	if len(dst)%size != 0 || len(dst) == 0 {
+		dst = append(dst, make([]byte, size-len(dst)%size)...)
+	}
+	return dst
+}
+
+// ISO9797Alg1 is the MAC algorithm 1 of ISO 9797-1, aka CBC-MAC.
+// It uses DES with a single length key (aka ANSI X9.9) or Triple DES with a double or triple length key.
+type ISO9797Alg1 struct {
+	Padding Padding
+}
+
+// Sum implements the Algorithm interface.
+func (a ISO9797Alg1) Sum(key, data []byte) ([]byte, error) {
+	b, err := des3.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cbc(b, a.Padding.pad(data, b.BlockSize())), nil
+}
+
+// ISO9797Alg3 is the MAC algorithm 3 of ISO 9797-1, aka Retail MAC or ANSI X9.19.
+// It requires a double length key: the CBC-MAC is computed with DES and the first half of the key,
+// then the last block is deciphered with the second half and enciphered again with the first one.
+type ISO9797Alg3 struct {
+	Padding Padding
+}
+
+// Sum implements the Algorithm interface.
+func (a ISO9797Alg3) Sum(key, data []byte) ([]byte, error) {
+	if len(key) != 2*des.BlockSize {
+		return nil, errors.Key
+	}
+	k1, err := des.NewCipher(key[:des.BlockSize])
+	if err != nil {
+		return nil, err
+	}
+	k2, err := des.NewCipher(key[des.BlockSize:])
+	if err != nil {
+		return nil, err
+	}
+	out := cbc(k1, a.Padding.pad(data, des.BlockSize))
+	k2.Decrypt(out, out)
+	k1.Encrypt(out, out)
+
+	return out, nil
+}
+
+// CMAC is the cipher-based MAC defined by NIST SP 800-38B (aka OMAC1).
+type CMAC struct {
+	// NewCipher returns the block cipher used with the key. AES is used by default.
+	NewCipher func(key []byte) (cipher.Block, error)
+}
+
+// Sum implements the Algorithm interface.
+func (a CMAC) Sum(key, data []byte) ([]byte, error) {
+	fn := a.NewCipher
+	if fn == nil {
+		fn = aes.NewCipher
+	}
+	b, err := fn(key)
+	if err != nil {
+		return nil, errors.Key
+	}
+	return cmac(b, data), nil
+}
+
+// HMACSHA256 is the keyed-hash MAC defined by RFC 2104 with SHA-256.
+type HMACSHA256 struct{}
+
+// Sum implements the Algorithm interface.
+func (HMACSHA256) Sum(key, data []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.Key
+	}
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// cbc returns the last block of the data enciphered in cipher block chaining mode with a null IV.
+func cbc(b cipher.Block, data []byte) []byte {
+	out := make([]byte, b.BlockSize())
+	for i := 0; i < len(data); i += len(out) {
+		xor(out, data[i:i+len(out)])
+		b.Encrypt(out, out)
+	}
+	return out
+}
+
+func cmac(b cipher.Block, data []byte) []byte {
+	var (
+		size   = b.BlockSize()
+		k1, k2 = subkeys(b)
+		n      = (len(data) + size - 1) / size
+		last   = make([]byte, size)
+	)
+	if n == 0 {
+		n = 1
+	}
+	if len(data) > 0 && len(data)%size == 0 {
+		copy(last, data[(n-1)*size:])
+		xor(last, k1)
+	} else {
+		copy(last, data[(n-1)*size:])
+		last[len(data)-(n-1)*size] = 0x80
+		xor(last, k2)
+	}
+	out := cbc(b, data[:(n-1)*size])
+	xor(out, last)
+	b.Encrypt(out, out)
+
+	return out
+}
+
+// subkeys generates the two CMAC subkeys.
+func subkeys(b cipher.Block) (k1, k2 []byte) {
+	l := make([]byte, b.BlockSize())
+	b.Encrypt(l, l)
+	k1 = double(l)
+	k2 = double(k1)
+	return
+}
+
+// double multiplies by x in the binary field: the Rb constant depends on the block size.
+func double(src []byte) []byte {
+	rb := byte(0x87)
+	if len(src) == des.BlockSize {
+		rb = 0x1B
+	}
+	dst := make([]byte, len(src))
+	for i := 0; i < len(src)-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[len(src)-1] = src[len(src)-1] << 1
+	if src[0]&0x80 != 0 {
+		dst[len(src)-1] ^= rb
+	}
+	return dst
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package mac computes and verifies the message authentication code (MAC) carried by the fields 64 and 128.
+package mac
+
+import (
+	"crypto/hmac"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// Size is the length in bytes of the MAC in a message.
+// Only the left-most bytes of the algorithm's output are kept.
+const Size = 8
+
+// List of the fields dedicated to the MAC.
+const (
+	// Primary is the MAC field of a message with only a primary bitmap.
+	Primary field.ID = 64
+	// Secondary is the MAC field of a message with a secondary bitmap.
+	Secondary field.ID = 128
+)
+
+// Algorithm computes a message authentication code.
+type Algorithm interface {
+	// Sum returns the MAC of data with the key.
+	Sum(key, data []byte) ([]byte, error)
+}
+
+// New returns a new instance of Signer using this algorithm.
+func New(alg Algorithm) *Signer {
+	return &Signer{alg: alg}
+}
+
+// Signer signs and verifies messages.
+type Signer struct {
+	alg Algorithm
+}
+
+// Sign computes the MAC of the message with the key and sets it in the field 64,
+// or in the field 128 if the message has a secondary bitmap.
+func (s *Signer) Sign(msg *iso8583.Message, key []byte) error {
+	id, data, err := Input(msg)
+	if err != nil {
+		return err
+	}
+	f, err := s.sum(id, key, data)
+	if err != nil {
+		return err
+	}
+	if msg.Data == nil {
+		msg.Data = iso8583.Fields{}
+	}
+	if id == Secondary {
+		// The primary MAC field is not used with a secondary bitmap.
+		delete(msg.Data, Primary)
+	}
+	msg.Data[id] = f
+
+	return nil
+}
+
+// Verify checks the MAC of the message with the key.
+// It returns the errors.MAC error if it's missing or does not match.
+// The MAC is computed over the message encoded again, see VerifyBytes to use the received bytes.
+func (s *Signer) Verify(msg *iso8583.Message, key []byte) error {
+	id, data, err := Input(msg)
+	if err != nil {
+		return err
+	}
+	return s.verify(msg, id, key, data)
+}
+
+// VerifyBytes decodes the frame in the message, then checks its MAC with the key.
+// The MAC is computed over the bytes of the frame as received, since encoding the message again
+// can differ from what was on the wire. The format, the header and the spec of msg are used to decode it.
+func (s *Signer) VerifyBytes(frame []byte, msg *iso8583.Message, key []byte) error {
+	if err := iso8583.Unmarshal(frame, msg); err != nil {
+		return err
+	}
+	id, data, err := InputBytes(frame, msg)
+	if err != nil {
+		return err
+	}
+	return s.verify(msg, id, key, data)
+}
+
+func (s *Signer) verify(msg *iso8583.Message, id field.ID, key, data []byte) error {
+	f, ok := msg.Data[id].(*field.Data)
+	if !ok {
+		return errors.MAC
+	}
+	want, err := f.Bytes()
+	if err != nil {
+		return errors.MAC
+	}
+	got, err := s.sum(id, key, data)
+	if err != nil {
+		return err
+	}
+	b, err := got.Bytes()
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(want, b) {
+		return errors.MAC
+	}
+	return nil
+}
+
+func (s *Signer) sum(id field.ID, key, data []byte) (*field.Data, error) {
+	sum, err := s.alg.Sum(key, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(sum) < Size {
+		return nil, errors.Length
+	}
+	return NewField(id, sum[:Size])
+}
+
+// Field returns the position of the MAC in the message: 128 if the message has a secondary bitmap, 64 otherwise.
+func Field(msg *iso8583.Message) field.ID {
+	for k := range msg.Data {
+		if k > Primary {
+			return Secondary
+		}
+	}
+	return Primary
+}
+
+// NewField returns the MAC field at this position with the given value.
+func NewField(id field.ID, mac []byte) (*field.Data, error) {
+	f := field.New(id)
+	err := f.SetBytes(mac)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Input returns the position of the MAC and the data to authenticate:
+// the encoded message from the MTI up to the MAC field, excluded, even if data elements follow it.
+func Input(msg *iso8583.Message) (field.ID, []byte, error) {
+	id := Field(msg)
+	f, err := NewField(id, make([]byte, Size))
+	if err != nil {
+		return 0, nil, err
+	}
+	mac, err := field.Marshal(f)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Works on a copy of the message to add a blank MAC and so, set its bit in the bitmap.
+	cp := *msg
+	cp.Header = false
+	cp.Data = make(iso8583.Fields, len(msg.Data)+1)
+	for k, v := range msg.Data {
+		cp.Data[k] = v
+	}
+	if id == Secondary {
+		delete(cp.Data, Primary)
+	}
+	cp.Data[id] = f
+	b, err := iso8583.Marshal(&cp)
+	if err != nil {
+		return 0, nil, err
+	}
+	// The data elements of the tertiary bitmap follow the MAC.
+	// The data to authenticate ends where the message encoded without them ends, except for the tertiary bitmap.
+	head := cp
+	head.Data = make(iso8583.Fields, len(cp.Data))
+	for k, v := range cp.Data {
+		if k <= id {
+			head.Data[k] = v
+		}
+	}
+	if len(head.Data) == len(cp.Data) {
+		return id, b[:len(b)-len(mac)], nil
+	}
+	h, err := iso8583.Marshal(&head)
+	if err != nil {
+		return 0, nil, err
+	}
+	size := 16
+	if cp.BinaryBitmap {
+		size = 8
+	}
+	n := len(h) - len(mac) + (cp.Bitmap().Len()-head.Bitmap().Len())*size
+	return id, b[:n], nil
+}
+
+// InputBytes returns the position of the MAC and the data to authenticate of the message decoded from the frame:
+// the bytes of the frame from the MTI up to the MAC field, excluded. The MAC must be the last data element.
+func InputBytes(frame []byte, msg *iso8583.Message) (field.ID, []byte, error) {
+	id := Field(msg)
+	f, ok := msg.Data[id].(*field.Data)
+	if !ok {
+		return 0, nil, errors.MAC
+	}
+	if list := msg.Bitmap().Fields(); list[len(list)-1] != id {
+		return 0, nil, errors.MAC
+	}
+	mac, err := field.Marshal(f)
+	if err != nil {
+		return 0, nil, errors.MAC
+	}
+	var start int
+	if msg.Header {
+		start = msg.Format.LenHeader()
+	}
+	if len(frame) < start+len(mac) {
+		return 0, nil, errors.Length
+	}
+	return id, frame[start : len(frame)-len(mac)], nil
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package mac_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/internal/des3"
+	"github.com/rvflash/iso8583/mac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+const (
+	nist = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+	now = "Now is the time for all "
+)
+
+func TestAlgorithm_Sum(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			alg       mac.Algorithm
+			key, data string
+			out       string
+			err       error
+		}{
+			// ANSI X9.9 and X9.19.
+			{alg: mac.ISO9797Alg1{}, key: "0123456789abcdef", data: hex.EncodeToString([]byte(now)), out: "70a30640cc76dd8b"},
+			{
+				alg:  mac.ISO9797Alg3{},
+				key:  "0123456789abcdeffedcba9876543210",
+				data: hex.EncodeToString([]byte(now)),
+				out:  "a1c72e74ea3fa9b6",
+			},
+			{alg: mac.ISO9797Alg3{}, key: "0123456789abcdef", err: errors.Key},
+			// RFC 4493.
+			{alg: mac.CMAC{}, key: "2b7e151628aed2a6abf7158809cf4f3c", out: "bb1d6929e95937287fa37d129b756746"},
+			{
+				alg:  mac.CMAC{},
+				key:  "2b7e151628aed2a6abf7158809cf4f3c",
+				data: nist[:32],
+				out:  "070a16b46b4d4144f79bdd9dd04a287c",
+			},
+			{
+				alg:  mac.CMAC{},
+				key:  "2b7e151628aed2a6abf7158809cf4f3c",
+				data: nist[:80],
+				out:  "dfa66747de9ae63030ca32611497c827",
+			},
+			{alg: mac.CMAC{}, key: "2b7e", err: errors.Key},
+			// NIST SP 800-38B with TDES.
+			{
+				alg:  mac.CMAC{NewCipher: des3.NewCipher},
+				key:  "4cf15134a2850dd58a3d10ba80570d38",
+				data: nist[:16],
+				out:  "4ff2ab813c53ce83",
+			},
+			// RFC 4231.
+			{
+				alg:  mac.HMACSHA256{},
+				key:  hex.EncodeToString([]byte("Jefe")),
+				data: hex.EncodeToString([]byte("what do ya want for nothing?")),
+				out:  "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+			},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tt.alg.Sum(unhex(tt.key), unhex(tt.data))
+			are.Equal(err, tt.err)
+			are.Equal(hex.EncodeToString(out), tt.out)
+		})
+	}
+}
+
+func newMessage(ids ...field.ID) *iso8583.Message {
+	m := &iso8583.Message{
+		MTI:  iso8583.NewMTI(iso8583.V1987, iso8583.NetworkManagement),
+		Data: iso8583.Fields{},
+	}
+	for _, id := range ids {
+		f := field.New(id)
+		f.Value = []byte("001")
+		m.Data[id] = f
+	}
+	return m
+}
+
+func TestSigner_Sign(t *testing.T) {
+	var (
+		are = is.New(t)
+		key = unhex("0123456789abcdeffedcba9876543210")
+		dt  = []struct {
+			msg *iso8583.Message
+			id  field.ID
+		}{
+			{msg: newMessage(), id: mac.Primary},
+			{msg: newMessage(70), id: mac.Secondary},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			s := mac.New(mac.ISO9797Alg3{})
+			are.Equal(s.Verify(tt.msg, key), errors.MAC)
+			are.NoErr(s.Sign(tt.msg, key))
+			are.Equal(mac.Field(tt.msg), tt.id)
+			f, ok := tt.msg.Data[tt.id].(*field.Data)
+			are.True(ok)
+			are.True(f.Valid())
+
+			// Computed over the message up to the MAC.
+			b, err := iso8583.Marshal(tt.msg)
+			are.NoErr(err)
+			sum, err := mac.ISO9797Alg3{}.Sum(key, b[:len(b)-2*mac.Size])
+			are.NoErr(err)
+			are.Equal(string(b[len(b)-2*mac.Size:]), fmt.Sprintf("%X", sum[:mac.Size]))
+
+			are.NoErr(s.Verify(tt.msg, key))
+			are.Equal(s.Verify(tt.msg, unhex("0123456789abcdef0123456789abcdef")), errors.MAC)
+		})
+	}
+}
+
+func TestSigner_VerifyBytes(t *testing.T) {
+	var (
+		are = is.New(t)
+		key = unhex("0123456789abcdeffedcba9876543210")
+		s   = mac.New(mac.ISO9797Alg3{})
+	)
+	// The PIN block is received in lower case: the message encoded again differs from the frame.
+	data := []byte("0000" + "0200" + "0000000000001001" + "1b9c1845eb993a7a")
+	sum, err := mac.ISO9797Alg3{}.Sum(key, data[4:])
+	are.NoErr(err)
+	frame := append(data, fmt.Sprintf("%X", sum[:mac.Size])...)
+	copy(frame, fmt.Sprintf("%04d", len(frame)-4))
+
+	m := &iso8583.Message{Header: true}
+	are.NoErr(s.VerifyBytes(frame, m, key))
+	are.Equal(m.Type(), "0200")
+	are.Equal(s.Verify(m, key), errors.MAC)
+	are.Equal(s.VerifyBytes(frame, &iso8583.Message{Header: true}, unhex("0123456789abcdef0123456789abcdef")), errors.MAC)
+
+	// The MAC must be the last data element.
+	m = newMessage(70)
+	are.NoErr(s.Sign(m, key))
+	m.Spec = field.ISO1987.Extend(field.Spec{130: {Format: field.Numeric, Size: 2}})
+	f := m.Spec.New(130)
+	f.Value = []byte("01")
+	m.Data[130] = f
+	frame, err = iso8583.Marshal(m)
+	are.NoErr(err)
+	are.Equal(s.VerifyBytes(frame, &iso8583.Message{Spec: m.Spec}, key), errors.MAC)
+}
+
+func TestInput(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = newMessage(70)
+	)
+	// The data element 130 of the tertiary bitmap follows the MAC.
+	m.Spec = field.ISO1987.Extend(field.Spec{130: {Format: field.Numeric, Size: 2}})
+	f := m.Spec.New(130)
+	f.Value = []byte("01")
+	m.Data[130] = f
+	for _, binary := range []bool{false, true} {
+		m.BinaryBitmap = binary
+		id, data, err := mac.Input(m)
+		are.NoErr(err)
+		are.Equal(id, mac.Secondary)
+		mf, err := mac.NewField(id, make([]byte, mac.Size))
+		are.NoErr(err)
+		m.Data[id] = mf
+		b, err := iso8583.Marshal(m)
+		are.NoErr(err)
+		delete(m.Data, id)
+		// The MAC and the field 130 are excluded.
+		are.Equal(string(data), string(b[:len(b)-2*mac.Size-2]))
+	}
+}
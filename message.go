@@ -6,14 +6,22 @@ package iso8583
 
 import (
 	"fmt"
-	"math"
+	"math/bits"
 	"sort"
+	"strings"
 
 	"github.com/rvflash/iso8583/encoding"
 	"github.com/rvflash/iso8583/errors"
 	"github.com/rvflash/iso8583/field"
 )
 
+// MaxField is the position of the last data element, with a tertiary bitmap.
+const MaxField = 192
+
+// DefaultMaxLength is the maximum length of a message to decode, header included,
+// when the message does not define it.
+const DefaultMaxLength = 1<<16 - 1
+
 // Field represents all message's fields.
 type Fields map[field.ID]field.Field
 
@@ -22,7 +30,49 @@ type Message struct {
 	MTI    *MTI
 	Format encoding.Format
 	Header bool
-	Data   Fields
+	// BinaryBitmap indicates that the bitmaps are written with 8 bytes each,
+	// instead of 16 hexadecimal characters in the character set of the Format.
+	BinaryBitmap bool
+	// Spec overrides the definition of some data elements.
+	// The others are defined by the built-in specification of the version of the MTI.
+	Spec field.Spec
+	// Strictness, if not lenient, overrides the level of validation of the characters of all the data elements,
+	// without changing their definition. It allows to reject the lower case or accented characters,
+	// or to skip the checks.
+	Strictness field.Strictness
+	// MaxLength caps the length of the message to decode, header included, against malicious inputs.
+	// If zero, DefaultMaxLength is used. If negative, the length is not limited.
+	MaxLength int
+	Data      Fields
+	// mem is the memory used by the last decoding, reused once reset.
+	mem *memory
+}
+
+// memory holds the values of a decoded message.
+type memory struct {
+	mti   MTI
+	data  []field.Data
+	buf   []byte
+	reset bool
+}
+
+// field returns a new data element, with this definition.
+func (mem *memory) field(e field.Element, id field.ID) *field.Data {
+	mem.data = append(mem.data, field.Data{Element: e, Pos: id})
+	return &mem.data[len(mem.data)-1]
+}
+
+// Reset clears the message to decode another one with the same format, header and specification.
+// The memory of the data elements previously decoded is reused, so they must no longer be used.
+func (m *Message) Reset() {
+	clear(m.Data)
+	m.MTI = nil
+	if m.mem == nil {
+		m.mem = new(memory)
+	}
+	m.mem.data = m.mem.data[:0]
+	m.mem.buf = m.mem.buf[:0]
+	m.mem.reset = true
 }
 
 // Fields returns the list of Field Elements.
@@ -30,6 +80,45 @@ func (m *Message) Fields() Fields {
 	return m.Data
 }
 
+// Specification returns the definition of the data elements used to encode or decode the message:
+// the built-in specification of the version of its MTI, extended with its Spec.
+// It is a new specification if both are defined: see Element to only get one definition.
+func (m *Message) Specification() field.Spec {
+	if m.MTI == nil {
+		return m.Spec
+	}
+	v := m.MTI.Version.Spec()
+	switch {
+	case len(m.Spec) == 0:
+		return v
+	case len(v) == 0:
+		return m.Spec
+	}
+	return v.Extend(m.Spec)
+}
+
+// Element returns the definition of the data element in the specification of the message,
+// without merging it, see Specification. Its strictness is the one of the message, if not lenient.
+func (m *Message) Element(id field.ID) field.Element {
+	e, ok := m.Spec[id]
+	if !ok {
+		var v field.Spec
+		if m.MTI != nil {
+			v = m.MTI.Version.Spec()
+		}
+		e = v.Element(id)
+	}
+	if m.Strictness != field.Lenient {
+		e.Strictness = m.Strictness
+	}
+	return e
+}
+
+// newData returns an empty data element, as defined by the specification of the message.
+func (m *Message) newData(id field.ID) *field.Data {
+	return &field.Data{Element: m.Element(id), Pos: id}
+}
+
 // Type returns the Message Type Indicator.
 func (m *Message) Type() string {
 	if m.MTI == nil || !m.MTI.Valid() {
@@ -38,85 +127,255 @@ func (m *Message) Type() string {
 	return m.MTI.String()
 }
 
-// todo
-// String returns the message as a string.
-// Dump string
+// String implements the fmt.Stringer interface.
+// It returns the canonical text of the message: the MTI then one line by data element,
+// sorted by position, with the binary data in hexadecimal and the sensitive values masked.
+func (m *Message) String() string {
+	var buf strings.Builder
+	buf.WriteString("MTI " + m.Type() + "\n")
+	for _, id := range m.ids() {
+		buf.WriteString(fmt.Sprintf("%03d %s\n", id, text(m.Data[field.ID(id)], true)))
+	}
+	return buf.String()
+}
 
-// bitmap extracts this data and returns the rest of the message.
-func (m *Message) bitmap(src []byte) (dst []byte, err error) {
-	var (
-		b, s string
-		a, z int
-	)
-	for {
-		z = a + m.Format.LenBitmap()
-		if len(src) < z {
-			return nil, errors.OutOfRange
-		}
-		s, err = m.Format.EncodeToBinary(src[a:z])
-		if err != nil {
-			return nil, err
+// Bitmap returns the bitmap of the data elements of the message.
+func (m *Message) Bitmap() Bitmap {
+	var b Bitmap
+	for id := range m.Data {
+		b.Set(id)
+	}
+	return b
+}
+
+// Has returns in success if the message has this data element.
+func (m *Message) Has(id field.ID) bool {
+	_, ok := m.Data[id]
+	return ok
+}
+
+// Get returns the data element at this position, if any.
+func (m *Message) Get(id field.ID) (field.Field, bool) {
+	f, ok := m.Data[id]
+	return f, ok
+}
+
+// Set sets the data element and updates the bitmap.
+func (m *Message) Set(f field.Field) error {
+	if !isData(f.ID()) {
+		return errors.New(errors.Data, int(f.ID()))
+	}
+	if m.Data == nil {
+		m.Data = Fields{}
+	}
+	m.Data[f.ID()] = f
+	m.sync()
+	return nil
+}
+
+// Delete removes the data element and updates the bitmap.
+func (m *Message) Delete(id field.ID) {
+	if !isData(id) || !m.Has(id) {
+		return
+	}
+	delete(m.Data, id)
+	m.sync()
+}
+
+// Range calls fn for each data element, except the bitmap, by ascending position.
+// It stops if fn returns false.
+func (m *Message) Range(fn func(id field.ID, f field.Field) bool) {
+	for _, id := range m.ids() {
+		if !fn(field.ID(id), m.Data[field.ID(id)]) {
+			return
 		}
-		b += s
+	}
+}
 
-		// The first byte indicates the presence of an other bitmap.
-		if s[0] == '1' {
-			a += m.Format.LenBitmap()
+// Clone returns a deep copy of the message, sharing only its specification.
+// The data elements which are not a field.Data are converted to it.
+func (m *Message) Clone() *Message {
+	c := &Message{
+		Format:       m.Format,
+		Header:       m.Header,
+		BinaryBitmap: m.BinaryBitmap,
+		Spec:         m.Spec,
+		Strictness:   m.Strictness,
+		MaxLength:    m.MaxLength,
+	}
+	if m.MTI != nil {
+		v := *m.MTI
+		c.MTI = &v
+	}
+	if m.Data == nil {
+		return c
+	}
+	c.Data = make(Fields, len(m.Data))
+	for id, f := range m.Data {
+		d := *m.data(f)
+		d.Value = append([]byte(nil), d.Value...)
+		c.Data[id] = &d
+	}
+	return c
+}
+
+// EqualOptions are the options of the comparison of two messages.
+type EqualOptions struct {
+	// Ignore lists the data elements not compared, like the transmission date and time.
+	Ignore []field.ID
+}
+
+// Equal returns in success if both messages have the same type and the same data elements.
+// The bitmaps, the formats and the headers are not compared.
+func (m *Message) Equal(other *Message, opts *EqualOptions) bool {
+	if m.Type() != other.Type() {
+		return false
+	}
+	ignored := make(map[field.ID]bool)
+	if opts != nil {
+		for _, id := range opts.Ignore {
+			ignored[id] = true
+		}
+	}
+	for _, id := range union(m, other) {
+		if ignored[id] {
 			continue
 		}
-		// Prepares the fields list
-		err = m.make([]byte(b))
-		if err != nil {
-			return nil, err
+		x, inM := m.Data[id]
+		y, inO := other.Data[id]
+		if inM != inO || inM && x.String() != y.String() {
+			return false
 		}
-		return src[z:], nil
 	}
+	return true
 }
 
-// elements converts the binary bitmap to a list of field positions.
-func (m *Message) elements() (list []int) {
-	f1, ok := m.Data[1]
-	if !ok {
-		return
+// sync sets the bitmap (field 1) with the data elements of the message.
+func (m *Message) sync() {
+	f1 := field.New(1)
+	f1.Value = []byte(m.Bitmap().String())
+	f1.Size = len(f1.Value)
+	m.Data[1] = f1
+}
+
+// bitmap extracts the bitmaps and returns the rest of the message.
+// The data elements are converted from the character set of the Format to ASCII.
+func (m *Message) bitmap(src []byte) (b Bitmap, dst []byte, err error) {
+	var n int
+	if m.BinaryBitmap {
+		n, err = b.decodeBinary(src)
+		dst = m.Format.ToASCII(src[n:])
+	} else {
+		src = m.Format.ToASCII(src)
+		n, err = b.decodeHex(src)
+		dst = src[n:]
 	}
-	for k, v := range f1.String() {
-		if v == '1' && k > 0 {
-			list = append(list, k+1)
-		}
+	if err != nil {
+		return b, nil, err
 	}
-	sort.Ints(list)
+	// Prepares the fields list
+	m.make(b)
 
-	return
+	return b, dst, nil
 }
 
 // fields sets the data elements based on the message and the known fields in the bitmap.
-func (m *Message) fields(data []byte, list []int) error {
+// The values are sliced out of data, except the binary ones. They must use all the data.
+func (m *Message) fields(data []byte, b Bitmap) error {
 	var (
 		a, s int
 		err  error
 	)
-	for _, v := range list {
-		if v > math.MaxInt8 {
-			return errors.New(errors.Data, v)
+	for v := 2; v <= b.Len()*64; v++ {
+		if !isData(field.ID(v)) || !b.IsSet(field.ID(v)) {
+			continue
 		}
-		f := field.New(field.ID(v))
+		f := m.mem.field(m.Element(field.ID(v)), field.ID(v))
 		s, err = f.FixedSize(data[a:])
 		if err != nil {
 			return errors.New(err, v)
 		}
-		err = field.Unmarshal(data[a:], f)
+		m.mem.buf, err = field.UnmarshalAppend(data[a:], f, m.mem.buf)
 		if err != nil {
-			fmt.Println(m.Data)
-			fmt.Println(string(data))
-			fmt.Println(a, s)
 			return errors.New(err, v)
 		}
 		m.Data[f.ID()] = f
 		a += s
 	}
+	if a != len(data) {
+		// Data remains after the last data element.
+		return errors.Length
+	}
 	return nil
 }
 
+// encode returns the type indicator, the bitmaps and the data elements, as expected by the Format.
+func (m *Message) encode() ([]byte, error) {
+	for _, v := range m.ids() {
+		if !isData(field.ID(v)) {
+			return nil, errors.New(errors.Data, v)
+		}
+	}
+	var (
+		b   = m.Bitmap()
+		buf = []byte(m.MTI.String())
+	)
+	if m.BinaryBitmap {
+		buf = m.Format.FromASCII(buf)
+		p, _ := b.MarshalBinary()
+		buf = append(buf, p...)
+	} else {
+		buf = append(buf, b.MarshalHex()...)
+	}
+	a := len(buf)
+	for _, v := range b.Fields() {
+		d, err := field.Marshal(m.strict(m.data(m.Data[v])))
+		if err != nil {
+			return nil, errors.New(err, int(v))
+		}
+		buf = append(buf, d...)
+	}
+	if m.BinaryBitmap {
+		return append(buf[:a], m.Format.FromASCII(buf[a:])...), nil
+	}
+	return m.Format.FromASCII(buf), nil
+}
+
+// ids returns the sorted list of the data elements positions, except the bitmap.
+func (m *Message) ids() (list []int) {
+	for k := range m.Data {
+		if k > 1 {
+			list = append(list, int(k))
+		}
+	}
+	sort.Ints(list)
+
+	return
+}
+
+// data returns the data element behind the field.
+func (m *Message) data(f field.Field) *field.Data {
+	if d, ok := f.(*field.Data); ok {
+		return d
+	}
+	d := m.newData(f.ID())
+	d.Value = []byte(f.String())
+	if d.Type != field.Fixed {
+		d.Size = len(d.Value)
+	}
+	return d
+}
+
+// strict returns the data element with the strictness of the message, if not lenient.
+func (m *Message) strict(d *field.Data) *field.Data {
+	if m.Strictness == field.Lenient || d.Strictness == m.Strictness {
+		return d
+	}
+	c := *d
+	c.Strictness = m.Strictness
+	return &c
+}
+
 // header extracts the header length is needed and returns the rest of the message.
 func (m *Message) header(src []byte) (dst []byte, err error) {
 	if !m.Header {
@@ -137,16 +396,25 @@ func (m *Message) header(src []byte) (dst []byte, err error) {
 }
 
 // make sets the bitmap as the first data elements.
-func (m *Message) make(bitmap []byte) error {
-	f1 := field.New(1)
-	f1.Size = len(bitmap)
-	err := field.Unmarshal(bitmap, f1)
-	if err != nil {
-		return err
+func (m *Message) make(b Bitmap) {
+	// The bitmap is also a data element.
+	size := bits.OnesCount64(b[0]) + bits.OnesCount64(b[1]) + bits.OnesCount64(b[2]) + 1
+	if cap(m.mem.data) < size {
+		m.mem.data = make([]field.Data, 0, size)
 	}
-	m.Data = Fields{1: f1}
-
-	return nil
+	if m.mem.buf == nil {
+		// Room for the bitmaps and a MAC.
+		m.mem.buf = make([]byte, 0, MaxField+64)
+	}
+	a := len(m.mem.buf)
+	m.mem.buf = b.appendBits(m.mem.buf)
+	f1 := m.mem.field(field.ISO1987.Element(1), 1)
+	f1.Value = m.mem.buf[a:len(m.mem.buf):len(m.mem.buf)]
+	f1.Size = len(f1.Value)
+	if m.Data == nil || len(m.Data) > 0 {
+		m.Data = make(Fields, size)
+	}
+	m.Data[1] = f1
 }
 
 // mti sets the message type indicator and returns the rest of the message.
@@ -154,9 +422,11 @@ func (m *Message) mti(src []byte) (dst []byte, err error) {
 	if len(src) < m.Format.LenMTI() {
 		return nil, errors.OutOfRange
 	}
-	m.MTI, err = ParseMTI(string(src[:m.Format.LenMTI()]))
+	m.MTI = nil
+	err = m.mem.mti.parse(m.Format.ToASCII(src[:m.Format.LenMTI()]))
 	if err != nil {
 		return nil, err
 	}
+	m.MTI = &m.mem.mti
 	return src[m.Format.LenMTI():], nil
 }
@@ -77,15 +77,20 @@ func (m *MTI) String() string {
 }
 
 func parse(s string) (*MTI, error) {
-	var (
-		d uint8
-		m = new(MTI)
-	)
-	for i, r := range s {
+	m := new(MTI)
+	if err := m.parse([]byte(s)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parse sets the Message Type Identifier with these four digits.
+func (m *MTI) parse(b []byte) error {
+	for i, r := range b {
 		if r < '0' || r > '9' {
-			return nil, errors.MTI
+			return errors.MTI
 		}
-		d = uint8(r - '0')
+		d := r - '0'
 		switch i {
 		case 0:
 			m.Version = Version(d)
@@ -98,7 +103,7 @@ func parse(s string) (*MTI, error) {
 		}
 	}
 	if !m.Valid() {
-		return nil, errors.MTI
+		return errors.MTI
 	}
-	return m, nil
+	return nil
 }
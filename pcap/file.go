@@ -0,0 +1,244 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// List of magic numbers of the capture files.
+const (
+	pcapMicro          = 0xA1B2C3D4
+	pcapNano           = 0xA1B23C4D
+	pcapngBlock        = 0x0A0D0D0A
+	pcapngOrder uint32 = 0x1A2B3C4D
+)
+
+// List of pcapng blocks.
+const (
+	interfaceBlock      = 0x00000001
+	simplePacketBlock   = 0x00000003
+	enhancedPacketBlock = 0x00000006
+)
+
+// maxBlock is the maximum length of a block or a packet.
+const maxBlock = 1 << 24
+
+// packet is a frame captured on an interface.
+type packet struct {
+	time     time.Time
+	linkType uint16
+	data     []byte
+}
+
+// packets reads the packets of a pcap or pcapng file.
+type packets interface {
+	next() (*packet, error)
+}
+
+// open detects the format of the file.
+func open(r io.Reader) (packets, error) {
+	br := bufio.NewReader(r)
+	b, err := br.Peek(4)
+	if err != nil {
+		return nil, errors.Data
+	}
+	if binary.BigEndian.Uint32(b) == pcapngBlock {
+		return &pcapng{r: br}, nil
+	}
+	return newPcap(br)
+}
+
+// pcap reads the libpcap file format.
+type pcap struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint16
+}
+
+func newPcap(r io.Reader) (*pcap, error) {
+	b := make([]byte, 24)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, errors.Data
+	}
+	p := &pcap{r: r}
+	for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch o.Uint32(b) {
+		case pcapMicro:
+			p.order = o
+		case pcapNano:
+			p.order, p.nano = o, true
+		}
+		if p.order != nil {
+			break
+		}
+	}
+	if p.order == nil {
+		return nil, errors.Data
+	}
+	p.linkType = uint16(p.order.Uint32(b[20:]))
+	return p, nil
+}
+
+func (p *pcap) next() (*packet, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	n := p.order.Uint32(b[8:])
+	if n > maxBlock {
+		return nil, errors.Length
+	}
+	var (
+		sec  = int64(p.order.Uint32(b))
+		frac = int64(p.order.Uint32(b[4:]))
+	)
+	if !p.nano {
+		frac *= int64(time.Microsecond)
+	}
+	pkt := &packet{time: time.Unix(sec, frac).UTC(), linkType: p.linkType, data: make([]byte, n)}
+	if _, err := io.ReadFull(p.r, pkt.data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return pkt, nil
+}
+
+// pcapng reads the pcapng file format.
+type pcapng struct {
+	r     io.Reader
+	order binary.ByteOrder
+	ifs   []iface
+}
+
+// iface is an interface described in the section.
+type iface struct {
+	linkType uint16
+	// perSec is the number of timestamp units by second.
+	perSec uint64
+}
+
+func (p *pcapng) next() (*packet, error) {
+	for {
+		typ, body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case interfaceBlock:
+			if len(body) < 8 {
+				return nil, errors.Data
+			}
+			p.ifs = append(p.ifs, iface{linkType: p.order.Uint16(body), perSec: p.resolution(body[8:])})
+		case enhancedPacketBlock:
+			if len(body) < 20 {
+				return nil, errors.Data
+			}
+			id := p.order.Uint32(body)
+			if int(id) >= len(p.ifs) {
+				return nil, errors.Data
+			}
+			var (
+				ts = uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+				n  = p.order.Uint32(body[12:])
+			)
+			if int(n) > len(body)-20 {
+				return nil, errors.Length
+			}
+			var (
+				f    = p.ifs[id]
+				sec  = ts / f.perSec
+				nsec = ts % f.perSec * uint64(time.Second) / f.perSec
+			)
+			return &packet{
+				time:     time.Unix(int64(sec), int64(nsec)).UTC(),
+				linkType: f.linkType,
+				data:     body[20 : 20+n],
+			}, nil
+		case simplePacketBlock:
+			if len(body) < 4 || len(p.ifs) == 0 {
+				return nil, errors.Data
+			}
+			n := p.order.Uint32(body)
+			if int(n) > len(body)-4 {
+				n = uint32(len(body) - 4)
+			}
+			return &packet{linkType: p.ifs[0].linkType, data: body[4 : 4+n]}, nil
+		}
+	}
+}
+
+// block returns the type and the body of the next block.
+// A section header block resets the byte order and the interfaces.
+func (p *pcapng) block() (typ uint32, body []byte, err error) {
+	b := make([]byte, 8)
+	if _, err = io.ReadFull(p.r, b); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, err
+		}
+		return 0, nil, io.EOF
+	}
+	if binary.BigEndian.Uint32(b) == pcapngBlock {
+		// The byte order is given by the magic, just after the length.
+		m := make([]byte, 4)
+		if _, err = io.ReadFull(p.r, m); err != nil {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		switch pcapngOrder {
+		case binary.BigEndian.Uint32(m):
+			p.order = binary.BigEndian
+		case binary.LittleEndian.Uint32(m):
+			p.order = binary.LittleEndian
+		default:
+			return 0, nil, errors.Data
+		}
+		p.ifs = nil
+		b = append(b, m...)
+	}
+	if p.order == nil {
+		return 0, nil, errors.Data
+	}
+	n := p.order.Uint32(b[4:])
+	if n < 12 || n%4 != 0 || n > maxBlock {
+		return 0, nil, errors.Length
+	}
+	body = make([]byte, n-8)
+	copy(body, b[8:])
+	if _, err = io.ReadFull(p.r, body[len(b)-8:]); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	// Drops the trailing length.
+	return p.order.Uint32(b), body[:len(body)-4], nil
+}
+
+// resolution returns the number of timestamp units by second, based on the options of the interface.
+func (p *pcapng) resolution(opts []byte) uint64 {
+	for len(opts) >= 4 {
+		code, n := p.order.Uint16(opts), int(p.order.Uint16(opts[2:]))
+		if code == 0 || len(opts) < 4+n {
+			break
+		}
+		if code == 9 && n == 1 {
+			switch v := opts[4]; {
+			case v&0x80 != 0 && v&0x7F <= 30:
+				return 1 << (v & 0x7F)
+			case v&0x80 == 0 && v <= 9:
+				return uint64(math.Pow10(int(v)))
+			}
+		}
+		opts = opts[4+(n+3)/4*4:]
+	}
+	// Microseconds by default.
+	return 1e6
+}
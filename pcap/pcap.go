@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package pcap extracts the ISO 8583 messages of the TCP streams of pcap or pcapng files.
+// Only offline parsing of IPv4 and IPv6 over Ethernet, Linux cooked, loopback or raw links is supported.
+package pcap
+
+import (
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/frame"
+)
+
+// Endpoint is the end of a TCP connection.
+type Endpoint struct {
+	IP   net.IP
+	Port uint16
+}
+
+// String implements the fmt.Stringer interface.
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.IP.String(), strconv.Itoa(int(e.Port)))
+}
+
+// Message is a message extracted from a TCP stream.
+type Message struct {
+	// Time is the capture time of the packet completing the frame.
+	Time     time.Time
+	Src, Dst Endpoint
+	// Frame is the frame without its length prefix.
+	Frame []byte
+	// Message is the decoded frame, nil if Err is not.
+	Message *iso8583.Message
+	Err     error
+}
+
+// Reader extracts the messages of a capture file.
+type Reader struct {
+	// Prefix is the length-prefix framing of the messages.
+	Prefix frame.Prefix
+	// Port filters the TCP connections by port, on both sides. Zero means all.
+	Port uint16
+	// New returns an empty message, with its format and specification, to unmarshal a frame.
+	// By default, the messages are in ASCII without header.
+	New func() *iso8583.Message
+}
+
+// flow is a direction of a TCP connection.
+type flow struct {
+	src, dst string
+}
+
+// Read returns the messages of the pcap or pcapng file, sorted by time.
+// A stream with an invalid framing is abandoned after returning the error as a message.
+func (r *Reader) Read(rd io.Reader) ([]*Message, error) {
+	src, err := open(rd)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out     []*Message
+		streams = make(map[flow]*stream)
+		buffers = make(map[flow][]byte)
+		broken  = make(map[flow]bool)
+	)
+	for {
+		p, err := src.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		seg, ok := decode(p)
+		if !ok || r.Port != 0 && seg.src.Port != r.Port && seg.dst.Port != r.Port {
+			continue
+		}
+		f := flow{src: seg.src.String(), dst: seg.dst.String()}
+		if broken[f] {
+			continue
+		}
+		s, ok := streams[f]
+		if !ok {
+			s = new(stream)
+			streams[f] = s
+		}
+		for _, data := range s.add(seg) {
+			buf := append(buffers[f], data...)
+			for {
+				n, b, err := r.Prefix.Split(buf, false)
+				if err != nil {
+					out = append(out, &Message{Time: p.time, Src: seg.src, Dst: seg.dst, Err: err})
+					broken[f] = true
+					break
+				}
+				if n == 0 {
+					break
+				}
+				out = append(out, r.message(p.time, seg, b))
+				buf = buf[n:]
+			}
+			buffers[f] = buf
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+func (r *Reader) message(t time.Time, seg *segment, b []byte) *Message {
+	m := &Message{
+		Time:  t,
+		Src:   seg.src,
+		Dst:   seg.dst,
+		Frame: append([]byte(nil), b...),
+	}
+	msg := new(iso8583.Message)
+	if r.New != nil {
+		msg = r.New()
+	}
+	if m.Err = iso8583.Unmarshal(m.Frame, msg); m.Err == nil {
+		m.Message = msg
+	}
+	return m
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package pcap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/pcap"
+)
+
+const (
+	request  = "0800823A0000000000000400000000000000042009061390000109061304200420001"
+	response = "081082200000020000000400000000000000042009061390000100301"
+)
+
+var (
+	client = pcap.Endpoint{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 40000}
+	server = pcap.Endpoint{IP: net.IPv4(10, 0, 0, 2).To4(), Port: 5300}
+	start  = time.Date(2019, 4, 20, 9, 6, 13, 0, time.UTC)
+)
+
+// tcp returns an Ethernet frame with the TCP segment.
+func tcp(src, dst pcap.Endpoint, seq uint32, flags byte, payload []byte) []byte {
+	var (
+		eth = make([]byte, 14)
+		ip  = make([]byte, 20)
+		seg = make([]byte, 20)
+	)
+	binary.BigEndian.PutUint16(eth[12:], 0x0800)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(40+len(payload)))
+	ip[8], ip[9] = 64, 6
+	copy(ip[12:], src.IP)
+	copy(ip[16:], dst.IP)
+	binary.BigEndian.PutUint16(seg, src.Port)
+	binary.BigEndian.PutUint16(seg[2:], dst.Port)
+	binary.BigEndian.PutUint32(seg[4:], seq)
+	seg[12], seg[13] = 5<<4, flags
+	return append(append(append(eth, ip...), seg...), payload...)
+}
+
+// packets returns the frames of the traffic: the request is sent in two segments,
+// received out of order and retransmitted.
+func packets() [][]byte {
+	var req, resp bytes.Buffer
+	_ = frame.Binary2.Write(&req, []byte(request))
+	_ = frame.Binary2.Write(&resp, []byte(response))
+	r := req.Bytes()
+	return [][]byte{
+		tcp(client, server, 999, 0x02, nil),
+		tcp(server, client, 4999, 0x12, nil),
+		tcp(client, server, 1000+20, 0x18, r[20:]),
+		tcp(client, server, 1000, 0x18, r[:20]),
+		tcp(client, server, 1000, 0x18, r[:30]),
+		tcp(server, client, 5000, 0x18, resp.Bytes()),
+		// Another protocol.
+		tcp(client, pcap.Endpoint{IP: server.IP, Port: 80}, 1, 0x18, []byte("GET / HTTP/1.1\r\n")),
+	}
+}
+
+func pcapFile(order binary.ByteOrder) []byte {
+	var buf bytes.Buffer
+	h := make([]byte, 24)
+	order.PutUint32(h, 0xA1B2C3D4)
+	order.PutUint16(h[4:], 2)
+	order.PutUint16(h[6:], 4)
+	order.PutUint32(h[16:], 65535)
+	order.PutUint32(h[20:], 1)
+	buf.Write(h)
+	for k, p := range packets() {
+		r := make([]byte, 16)
+		order.PutUint32(r, uint32(start.Unix()))
+		order.PutUint32(r[4:], uint32(k*1000))
+		order.PutUint32(r[8:], uint32(len(p)))
+		order.PutUint32(r[12:], uint32(len(p)))
+		buf.Write(r)
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+func block(typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := make([]byte, 8, 12+len(body))
+	binary.LittleEndian.PutUint32(b, typ)
+	binary.LittleEndian.PutUint32(b[4:], uint32(12+len(body)))
+	b = append(b, body...)
+	return append(b, b[4:8]...)
+}
+
+func pcapngFile() []byte {
+	var buf bytes.Buffer
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb, 0x1A2B3C4D)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	buf.Write(block(0x0A0D0D0A, shb))
+	// Ethernet, with a resolution in nanoseconds.
+	idb := make([]byte, 8, 20)
+	binary.LittleEndian.PutUint16(idb, 1)
+	idb = append(idb, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0)
+	buf.Write(block(1, idb))
+	for k, p := range packets() {
+		ts := uint64(start.UnixNano()) + uint64(k)*uint64(time.Millisecond)
+		epb := make([]byte, 20)
+		binary.LittleEndian.PutUint32(epb[4:], uint32(ts>>32))
+		binary.LittleEndian.PutUint32(epb[8:], uint32(ts))
+		binary.LittleEndian.PutUint32(epb[12:], uint32(len(p)))
+		binary.LittleEndian.PutUint32(epb[16:], uint32(len(p)))
+		buf.Write(block(6, append(epb, p...)))
+	}
+	return buf.Bytes()
+}
+
+func TestReader_Read(t *testing.T) {
+	are := is.New(t)
+	for _, b := range [][]byte{pcapFile(binary.LittleEndian), pcapFile(binary.BigEndian), pcapngFile()} {
+		r := &pcap.Reader{Prefix: frame.Binary2, Port: server.Port}
+		out, err := r.Read(bytes.NewReader(b))
+		are.NoErr(err)
+		are.Equal(len(out), 2)
+
+		are.NoErr(out[0].Err)
+		are.Equal(out[0].Src.String(), "10.0.0.1:40000")
+		are.Equal(out[0].Dst.String(), "10.0.0.2:5300")
+		// The request ends with the third packet.
+		are.Equal(out[0].Time, start.Add(3*time.Millisecond))
+		are.Equal(string(out[0].Frame), request)
+		are.Equal(out[0].Message.Type(), "0800")
+
+		are.NoErr(out[1].Err)
+		are.Equal(out[1].Src, server)
+		are.Equal(out[1].Message.Type(), "0810")
+		are.Equal(out[1].Message.Data[39].String(), "00")
+	}
+}
+
+func TestReader_Read_Errors(t *testing.T) {
+	are := is.New(t)
+	// All the ports: the HTTP request is not a valid frame.
+	r := &pcap.Reader{Prefix: frame.ASCII4}
+	out, err := r.Read(bytes.NewReader(pcapngFile()))
+	are.NoErr(err)
+	for _, m := range out {
+		are.True(m.Err != nil)
+	}
+	_, err = r.Read(bytes.NewReader([]byte("not a capture file, really")))
+	are.Equal(err, errors.Data)
+	b := pcapFile(binary.LittleEndian)
+	_, err = r.Read(bytes.NewReader(b[:len(b)-1]))
+	are.Equal(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// List of supported link types.
+const (
+	linkNull     = 0
+	linkEthernet = 1
+	linkRaw      = 101
+	linkLinuxSLL = 113
+	linkLoop     = 108
+	linkIPv4     = 228
+	linkIPv6     = 229
+	linkSLL2     = 276
+)
+
+// List of ether types.
+const (
+	etherIPv4 = 0x0800
+	etherIPv6 = 0x86DD
+	etherVLAN = 0x8100
+	etherQinQ = 0x88A8
+)
+
+const (
+	protoTCP = 6
+	flagSYN  = 0x02
+)
+
+// segment is a TCP segment.
+type segment struct {
+	src, dst Endpoint
+	seq      uint32
+	flags    byte
+	payload  []byte
+}
+
+// decode returns the TCP segment carried by the packet, if any.
+func decode(p *packet) (*segment, bool) {
+	ip, ok := network(p.linkType, p.data)
+	if !ok || len(ip) == 0 {
+		return nil, false
+	}
+	var (
+		src, dst net.IP
+		proto    byte
+		tcp      []byte
+	)
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return nil, false
+		}
+		n, total := int(ip[0]&0x0F)*4, int(binary.BigEndian.Uint16(ip[2:]))
+		// Fragments are not supported.
+		if n < 20 || total < n || len(ip) < total || binary.BigEndian.Uint16(ip[6:])&0x3FFF != 0 {
+			return nil, false
+		}
+		src, dst, proto, tcp = net.IP(ip[12:16]), net.IP(ip[16:20]), ip[9], ip[n:total]
+	case 6:
+		if len(ip) < 40 {
+			return nil, false
+		}
+		n := 40 + int(binary.BigEndian.Uint16(ip[4:]))
+		if len(ip) < n {
+			return nil, false
+		}
+		src, dst, proto, tcp = net.IP(ip[8:24]), net.IP(ip[24:40]), ip[6], ip[40:n]
+		// Skips the extension headers: hop-by-hop, routing and destination options.
+		for (proto == 0 || proto == 43 || proto == 60) && len(tcp) >= 8 {
+			l := (int(tcp[1]) + 1) * 8
+			if len(tcp) < l {
+				return nil, false
+			}
+			proto, tcp = tcp[0], tcp[l:]
+		}
+	default:
+		return nil, false
+	}
+	if proto != protoTCP || len(tcp) < 20 {
+		return nil, false
+	}
+	n := int(tcp[12]>>4) * 4
+	if n < 20 || len(tcp) < n {
+		return nil, false
+	}
+	return &segment{
+		src:     Endpoint{IP: copyIP(src), Port: binary.BigEndian.Uint16(tcp)},
+		dst:     Endpoint{IP: copyIP(dst), Port: binary.BigEndian.Uint16(tcp[2:])},
+		seq:     binary.BigEndian.Uint32(tcp[4:]),
+		flags:   tcp[13],
+		payload: tcp[n:],
+	}, true
+}
+
+// network returns the network layer of the frame.
+func network(linkType uint16, b []byte) ([]byte, bool) {
+	switch linkType {
+	case linkEthernet:
+		if len(b) < 14 {
+			return nil, false
+		}
+		t, b := binary.BigEndian.Uint16(b[12:]), b[14:]
+		for (t == etherVLAN || t == etherQinQ) && len(b) >= 4 {
+			t, b = binary.BigEndian.Uint16(b[2:]), b[4:]
+		}
+		return b, t == etherIPv4 || t == etherIPv6
+	case linkNull, linkLoop:
+		// The family is in the byte order of the host: only the IP version matters.
+		if len(b) < 4 {
+			return nil, false
+		}
+		return b[4:], true
+	case linkRaw, linkIPv4, linkIPv6:
+		return b, true
+	case linkLinuxSLL:
+		if len(b) < 16 {
+			return nil, false
+		}
+		t := binary.BigEndian.Uint16(b[14:])
+		return b[16:], t == etherIPv4 || t == etherIPv6
+	case linkSLL2:
+		if len(b) < 20 {
+			return nil, false
+		}
+		t := binary.BigEndian.Uint16(b)
+		return b[20:], t == etherIPv4 || t == etherIPv6
+	default:
+		return nil, false
+	}
+}
+
+func copyIP(ip net.IP) net.IP {
+	return append(net.IP(nil), ip...)
+}
+
+// stream reassembles one direction of a TCP connection.
+type stream struct {
+	started bool
+	next    uint32
+	// pending are the payloads received out of order, by sequence number.
+	pending map[uint32][]byte
+}
+
+// add adds the segment and returns the data now in order.
+func (s *stream) add(seg *segment) [][]byte {
+	if seg.flags&flagSYN != 0 {
+		s.started, s.next, s.pending = true, seg.seq+1, nil
+		return nil
+	}
+	if len(seg.payload) == 0 {
+		return nil
+	}
+	if !s.started {
+		// Capture started in the middle of the connection.
+		s.started, s.next = true, seg.seq
+	}
+	if s.pending == nil {
+		s.pending = make(map[uint32][]byte)
+	}
+	if b, ok := s.pending[seg.seq]; !ok || len(b) < len(seg.payload) {
+		s.pending[seg.seq] = seg.payload
+	}
+	var out [][]byte
+	for {
+		var progress bool
+		for seq, b := range s.pending {
+			d := int32(s.next - seq)
+			switch {
+			case d < 0:
+				// Not yet.
+				continue
+			case int(d) >= len(b):
+				// Retransmission.
+				delete(s.pending, seq)
+			default:
+				delete(s.pending, seq)
+				out = append(out, b[d:])
+				s.next += uint32(len(b)) - uint32(d)
+				progress = true
+			}
+		}
+		if !progress {
+			return out
+		}
+	}
+}
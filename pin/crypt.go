@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package pin
+
+import (
+	"crypto/aes"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/internal/des3"
+)
+
+// Encrypt returns the enciphered PIN block.
+// The formats 0, 1 and 3 use DES or Triple DES, depending on the key length, the format 4 uses AES.
+func (f Format) Encrypt(key []byte, pin, pan string) ([]byte, error) {
+	block, err := f.Encode(pin, pan)
+	if err != nil {
+		return nil, err
+	}
+	if f != ISO4 {
+		c, err := des3.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return des3.EncryptECB(c, block)
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Key
+	}
+	p, err := panField4(pan)
+	if err != nil {
+		return nil, err
+	}
+	c.Encrypt(block, block)
+	block = xor(block, p)
+	c.Encrypt(block, block)
+
+	return block, nil
+}
+
+// Decrypt returns the PIN inside the enciphered PIN block.
+func (f Format) Decrypt(key, block []byte, pan string) (string, error) {
+	if len(block) != f.BlockSize() {
+		return "", errors.Length
+	}
+	if f != ISO4 {
+		c, err := des3.NewCipher(key)
+		if err != nil {
+			return "", err
+		}
+		b, err := des3.DecryptECB(c, block)
+		if err != nil {
+			return "", err
+		}
+		return f.Decode(b, pan)
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return "", errors.Key
+	}
+	p, err := panField4(pan)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, AESBlockSize)
+	c.Decrypt(b, block)
+	b = xor(b, p)
+	c.Decrypt(b, b)
+
+	return f.Decode(b, pan)
+}
+
+// panField4 returns the plain text account number field used by the format 4:
+// the length of the PAN minus 12, followed by the PAN left justified, at least 12 digits, padded with zeros.
+func panField4(pan string) ([]byte, error) {
+	const minLen, maxLen = 12, 19
+	if pan == "" || len(pan) > maxLen || !isDigits(pan) {
+		return nil, errors.Data
+	}
+	n := make([]byte, 2*AESBlockSize)
+	if len(pan) < minLen {
+		pan = strings.Repeat("0", minLen-len(pan)) + pan
+	}
+	n[0] = byte(len(pan) - minLen)
+	for i := range pan {
+		n[i+1] = pan[i] - '0'
+	}
+	return pack(n), nil
+}
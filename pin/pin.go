@@ -0,0 +1,223 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package pin implements the PIN block formats 0, 1, 3 and 4 as defined in ISO 9564-1.
+// The enciphered PIN block is carried by the field 52 of an ISO 8583 message.
+package pin
+
+import (
+	"crypto/rand"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// Format is a PIN block format.
+type Format uint8
+
+// List of supported formats.
+const (
+	// ISO0 combines the PIN with the account number, padded with 'F' (aka ANSI X9.8).
+	ISO0 Format = 0
+	// ISO1 combines the PIN with random digits, when no account number is available.
+	ISO1 Format = 1
+	// ISO3 combines the PIN with the account number, padded with random digits from 'A' to 'F'.
+	ISO3 Format = 3
+	// ISO4 combines the PIN with the account number in two 16-byte fields enciphered with AES.
+	ISO4 Format = 4
+)
+
+// Block sizes in bytes.
+const (
+	BlockSize    = 8
+	AESBlockSize = 16
+)
+
+// PIN length bounds.
+const (
+	MinLen = 4
+	MaxLen = 12
+)
+
+// Field is the position of the PIN data in an ISO 8583 message.
+const Field field.ID = 52
+
+// NewField returns the field 52 with the given enciphered PIN block as value.
+func NewField(block []byte) (*field.Data, error) {
+	f := field.New(Field)
+	err := f.SetBytes(block)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Valid validates the Format.
+func (f Format) Valid() bool {
+	switch f {
+	case ISO0, ISO1, ISO3, ISO4:
+		return true
+	default:
+		return false
+	}
+}
+
+// BlockSize returns the size of the PIN block in bytes.
+func (f Format) BlockSize() int {
+	if f == ISO4 {
+		return AESBlockSize
+	}
+	return BlockSize
+}
+
+// Encode returns the clear PIN block built with the PIN and the primary account number.
+// For the format 4, it returns the plain text PIN field, the account number being only used on encipherment.
+func (f Format) Encode(pin, pan string) ([]byte, error) {
+	if !f.Valid() {
+		return nil, errors.NotImplemented
+	}
+	if !isPIN(pin) {
+		return nil, errors.PIN
+	}
+	b, err := f.pinField(pin)
+	if err != nil {
+		return nil, err
+	}
+	if f == ISO1 || f == ISO4 {
+		return b, nil
+	}
+	p, err := panField(pan)
+	if err != nil {
+		return nil, err
+	}
+	return xor(b, p), nil
+}
+
+// Decode returns the PIN inside the clear PIN block.
+// For the format 4, the block must be the plain text PIN field.
+func (f Format) Decode(block []byte, pan string) (string, error) {
+	if !f.Valid() {
+		return "", errors.NotImplemented
+	}
+	if len(block) != f.BlockSize() {
+		return "", errors.Length
+	}
+	if f == ISO0 || f == ISO3 {
+		p, err := panField(pan)
+		if err != nil {
+			return "", err
+		}
+		block = xor(block, p)
+	}
+	n := nibbles(block)
+	if n[0] != byte(f) || n[1] < MinLen || n[1] > MaxLen {
+		return "", errors.PIN
+	}
+	pin := make([]byte, n[1])
+	for i := range pin {
+		if n[i+2] > 9 {
+			return "", errors.PIN
+		}
+		pin[i] = '0' + n[i+2]
+	}
+	for _, v := range n[2+len(pin) : 2*BlockSize] {
+		if !f.isFill(v) {
+			return "", errors.PIN
+		}
+	}
+	return string(pin), nil
+}
+
+func (f Format) isFill(v byte) bool {
+	switch f {
+	case ISO0:
+		return v == 0xF
+	case ISO3:
+		return v >= 0xA
+	case ISO4:
+		return v == 0xA
+	default:
+		return true
+	}
+}
+
+// pinField returns the PIN field: the control field, the PIN length, the PIN and the fill digits.
+func (f Format) pinField(pin string) ([]byte, error) {
+	n := make([]byte, 2*f.BlockSize())
+	_, err := rand.Read(n)
+	if err != nil {
+		return nil, err
+	}
+	n[0] = byte(f)
+	n[1] = byte(len(pin))
+	for i := 2; i < len(n); i++ {
+		switch {
+		case i < len(pin)+2:
+			n[i] = pin[i-2] - '0'
+		case f == ISO0:
+			n[i] = 0xF
+		case f == ISO3:
+			n[i] = 0xA + n[i]%6
+		case f == ISO4 && i < 2*BlockSize:
+			n[i] = 0xA
+		default:
+			n[i] &= 0xF
+		}
+	}
+	return pack(n), nil
+}
+
+// panField returns the account number field used by the formats 0 and 3:
+// the 12 right-most digits of the PAN excluding the check digit.
+func panField(pan string) ([]byte, error) {
+	if len(pan) < 2 || !isDigits(pan) {
+		return nil, errors.Data
+	}
+	pan = pan[:len(pan)-1]
+	n := make([]byte, 2*BlockSize)
+	for i, j := len(n)-1, len(pan)-1; i >= 4 && j >= 0; i, j = i-1, j-1 {
+		n[i] = pan[j] - '0'
+	}
+	return pack(n), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isPIN(s string) bool {
+	return len(s) >= MinLen && len(s) <= MaxLen && isDigits(s)
+}
+
+// nibbles splits each byte in two half-bytes.
+func nibbles(b []byte) []byte {
+	n := make([]byte, 2*len(b))
+	for i, v := range b {
+		n[2*i] = v >> 4
+		n[2*i+1] = v & 0xF
+	}
+	return n
+}
+
+// pack joins the half-bytes two by two.
+func pack(n []byte) []byte {
+	b := make([]byte, len(n)/2)
+	for i := range b {
+		b[i] = n[2*i]<<4 | n[2*i+1]
+	}
+	return b
+}
+
+func xor(a, b []byte) []byte {
+	dst := make([]byte, len(a))
+	for i := range a {
+		dst[i] = a[i] ^ b[i]
+	}
+	return dst
+}
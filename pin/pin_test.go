@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package pin_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/pin"
+)
+
+const pan = "4012345678909"
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestFormat_Encode(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			fmt      pin.Format
+			pin, pan string
+			out      string
+			err      error
+		}{
+			{fmt: pin.ISO0, pin: "1234", pan: pan, out: "041274EDCBA9876F"},
+			{fmt: pin.ISO0, pin: "123456789012", pan: "12", out: "0C123456789012FE"},
+			{fmt: pin.ISO0, pin: "123", pan: pan, err: errors.PIN},
+			{fmt: pin.ISO0, pin: "1234567890123", pan: pan, err: errors.PIN},
+			{fmt: pin.ISO0, pin: "12a4", pan: pan, err: errors.PIN},
+			{fmt: pin.ISO0, pin: "1234", pan: "40123456789O9", err: errors.Data},
+			{fmt: pin.ISO3, pin: "1234", pan: "", err: errors.Data},
+			{fmt: 2, pin: "1234", pan: pan, err: errors.NotImplemented},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tt.fmt.Encode(tt.pin, tt.pan)
+			are.Equal(err, tt.err)
+			if tt.err == nil {
+				are.Equal(hex.EncodeToString(out), hex.EncodeToString(unhex(tt.out)))
+			}
+		})
+	}
+}
+
+func TestFormat_Decode(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			fmt   pin.Format
+			block string
+			pan   string
+			out   string
+			err   error
+		}{
+			{fmt: pin.ISO0, block: "041274EDCBA9876F", pan: pan, out: "1234"},
+			{fmt: pin.ISO0, block: "041274EDCBA9876F", pan: "4012345678919", err: errors.PIN},
+			{fmt: pin.ISO1, block: "141234A5F2C6B07E", out: "1234"},
+			{fmt: pin.ISO1, block: "131234A5F2C6B07E", err: errors.PIN},
+			{fmt: pin.ISO3, block: "341274B9F9B9D35D", pan: pan, out: "1234"},
+			{fmt: pin.ISO3, block: "041274EDCBA9876F", pan: pan, err: errors.PIN},
+			{fmt: pin.ISO4, block: "441234AAAAAAAAAA2F69058DE86B3BA5", out: "1234"},
+			{fmt: pin.ISO4, block: "441234FFFFFFFFFF2F69058DE86B3BA5", err: errors.PIN},
+			{fmt: pin.ISO4, block: "041274EDCBA9876F", err: errors.Length},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tt.fmt.Decode(unhex(tt.block), tt.pan)
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+		})
+	}
+}
+
+func TestFormat_Encrypt(t *testing.T) {
+	var (
+		are = is.New(t)
+		key = unhex("042666B49184CFA368DE9628D0397BC9")
+	)
+	for _, f := range []pin.Format{pin.ISO0, pin.ISO1, pin.ISO3, pin.ISO4} {
+		k := key
+		if f == pin.ISO4 {
+			k = unhex("00112233445566778899AABBCCDDEEFF00112233445566778899AABBCCDDEEFF")
+		}
+		out, err := f.Encrypt(k, "987654", pan)
+		are.NoErr(err)
+		are.Equal(len(out), f.BlockSize())
+		p, err := f.Decrypt(k, out, pan)
+		are.NoErr(err)
+		are.Equal(p, "987654")
+	}
+	// Format 4 known answer, computed step by step with AES-128: the plain text PIN field
+	// 441234AAAAAAAAAA2F69058DE86B3BA5 is enciphered, added to the plain text PAN field
+	// 71234567890123456789000000000000 and enciphered again.
+	k := unhex("00112233445566778899AABBCCDDEEFF")
+	p, err := pin.ISO4.Decrypt(k, unhex("88DC8C371697E5C9C048E3B38EB6F418"), "1234567890123456789")
+	are.NoErr(err)
+	are.Equal(p, "1234")
+
+	// Format 4 binds the PIN to the account number.
+	out, err := pin.ISO4.Encrypt(k, "1234", "1234567890123456789")
+	are.NoErr(err)
+	_, err = pin.ISO4.Decrypt(k, out, "1234567890123456788")
+	are.Equal(err, errors.PIN)
+
+	_, err = pin.ISO0.Encrypt([]byte("short"), "1234", pan)
+	are.Equal(err, errors.Key)
+	_, err = pin.ISO4.Encrypt(key[:8], "1234", pan)
+	are.Equal(err, errors.Key)
+}
+
+func TestNewField(t *testing.T) {
+	are := is.New(t)
+	f, err := pin.NewField(unhex("1B9C1845EB993A7A"))
+	are.NoErr(err)
+	are.Equal(f.ID(), pin.Field)
+	are.True(f.Valid())
+	b, err := f.Bytes()
+	are.NoErr(err)
+	are.Equal(hex.EncodeToString(b), "1b9c1845eb993a7a")
+
+	_, err = pin.NewField(make([]byte, pin.AESBlockSize))
+	are.Equal(err, errors.Length)
+}
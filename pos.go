@@ -0,0 +1,329 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// EntryMode is the way the card data have been read.
+type EntryMode uint8
+
+// List of entry modes.
+const (
+	UnknownEntry EntryMode = iota
+	ManualEntry
+	MagneticStripe
+	BarCode
+	OCR
+	Chip
+	Contactless
+	ContactlessMagneticStripe
+	ChipFallback
+	ECommerce
+	CredentialOnFile
+)
+
+var entryModes = [...]string{
+	"unknown", "manual", "magnetic stripe", "bar code", "OCR", "chip", "contactless",
+	"contactless magnetic stripe", "chip fallback", "e-commerce", "credential on file",
+}
+
+// String implements the fmt.Stringer interface.
+func (e EntryMode) String() string {
+	if int(e) < len(entryModes) {
+		return entryModes[e]
+	}
+	return entryModes[UnknownEntry]
+}
+
+// PINCapability is the ability of the terminal to capture a PIN.
+type PINCapability uint8
+
+// List of PIN capabilities.
+const (
+	UnknownPINCapability PINCapability = iota
+	PINCapable
+	NoPINCapability
+	PINPadInoperative
+)
+
+// CardholderPresence indicates if the cardholder is present, or how the order has been placed.
+type CardholderPresence uint8
+
+// List of cardholder presences.
+const (
+	UnknownPresence CardholderPresence = iota
+	CardholderPresent
+	CardholderNotPresent
+	MailOrder
+	TelephoneOrder
+	RecurringOrder
+	ElectronicOrder
+)
+
+// POSEntry describes how the card data and the cardholder have been captured at the point of service.
+type POSEntry struct {
+	Mode       EntryMode
+	PIN        PINCapability
+	Cardholder CardholderPresence
+	// PINLength is the maximum number of PIN characters the terminal can capture, 0 if unknown.
+	PINLength int
+	// Raw is the value of the field 22.
+	Raw string
+}
+
+// entryModes1987 lists the PAN entry modes of ISO 8583:1987, including the common private values.
+var entryModes1987 = map[string]EntryMode{
+	"00": UnknownEntry,
+	"01": ManualEntry,
+	"02": MagneticStripe,
+	"03": BarCode,
+	"04": OCR,
+	"05": Chip,
+	"07": Contactless,
+	"10": CredentialOnFile,
+	"79": ChipFallback,
+	"80": ChipFallback,
+	"81": ECommerce,
+	"90": MagneticStripe,
+	"91": ContactlessMagneticStripe,
+	"95": Chip,
+}
+
+// ParsePOSEntryMode parses the point of service entry mode of ISO 8583:1987 (n 3):
+// the PAN entry mode then the PIN entry capability.
+func ParsePOSEntryMode(s string) (*POSEntry, error) {
+	if len(s) != 3 || !digits(s) {
+		return nil, errors.Data
+	}
+	e := &POSEntry{Mode: entryModes1987[s[:2]], Raw: s}
+	switch s[2] {
+	case '1':
+		e.PIN = PINCapable
+	case '2':
+		e.PIN = NoPINCapability
+	case '8':
+		e.PIN = PINPadInoperative
+	}
+	if e.Mode == ECommerce {
+		e.Cardholder = ElectronicOrder
+	}
+	return e, nil
+}
+
+// ParsePOSDataCode parses the point of service data code of ISO 8583:1993 and 2003 (an 12).
+// Only the card data input mode (7), the cardholder authentication capability (2),
+// the cardholder presence (5) and the PIN capture capability (12) are decoded.
+func ParsePOSDataCode(s string) (*POSEntry, error) {
+	if len(s) != 12 {
+		return nil, errors.Data
+	}
+	for k := 0; k < len(s); k++ {
+		if !isAlphanumeric(s[k]) {
+			return nil, errors.Data
+		}
+	}
+	e := &POSEntry{Raw: s}
+	switch s[6] {
+	case '1', '6':
+		e.Mode = ManualEntry
+	case '2':
+		e.Mode = MagneticStripe
+	case '3':
+		e.Mode = BarCode
+	case '4':
+		e.Mode = OCR
+	case '5':
+		e.Mode = Chip
+	}
+	switch s[1] {
+	case '0':
+		e.PIN = NoPINCapability
+	case '1':
+		e.PIN = PINCapable
+	case '5':
+		e.PIN = PINPadInoperative
+	}
+	switch s[4] {
+	case '0':
+		e.Cardholder = CardholderPresent
+	case '1':
+		e.Cardholder = CardholderNotPresent
+	case '2':
+		e.Cardholder = MailOrder
+	case '3':
+		e.Cardholder = TelephoneOrder
+	case '4':
+		e.Cardholder = RecurringOrder
+	case '5':
+		e.Cardholder = ElectronicOrder
+		if e.Mode == UnknownEntry || e.Mode == ManualEntry {
+			e.Mode = ECommerce
+		}
+	}
+	// The PIN capture capability is the maximum length of the PIN, from 4 to C in hexadecimal.
+	if n, err := strconv.ParseUint(s[11:], 16, 8); err == nil && n >= 4 {
+		e.PINLength = int(n)
+	}
+	return e, nil
+}
+
+// POSCondition is the point of service condition code of ISO 8583:1987 (field 25).
+type POSCondition uint8
+
+// List of condition codes.
+const (
+	NormalPresentment           POSCondition = 0
+	CustomerNotPresent          POSCondition = 1
+	UnattendedTerminal          POSCondition = 2
+	MerchantSuspicious          POSCondition = 3
+	ElectronicCashRegister      POSCondition = 4
+	CustomerPresentCardNot      POSCondition = 5
+	PreAuthorized               POSCondition = 6
+	TelephoneDevice             POSCondition = 7
+	MailTelephoneOrder          POSCondition = 8
+	SecurityAlert               POSCondition = 9
+	CustomerIdentityVerified    POSCondition = 10
+	SuspectedFraud              POSCondition = 11
+	SecurityReasons             POSCondition = 12
+	RepresentationOfItem        POSCondition = 13
+	PublicUtilityTerminal       POSCondition = 14
+	CustomerTerminal            POSCondition = 15
+	AdministrativeTerminal      POSCondition = 16
+	ReturnedItem                POSCondition = 17
+	NoChequeInEnvelope          POSCondition = 18
+	DepositOutOfBalance         POSCondition = 19
+	PaymentOutOfBalance         POSCondition = 20
+	ManualReversal              POSCondition = 21
+	TerminalErrorCounted        POSCondition = 22
+	TerminalErrorNotCounted     POSCondition = 23
+	AccountVerificationRequest  POSCondition = 51
+	ElectronicCommerceCondition POSCondition = 59
+)
+
+var posConditions = map[POSCondition]string{
+	NormalPresentment:           "normal presentment",
+	CustomerNotPresent:          "customer not present",
+	UnattendedTerminal:          "unattended terminal able to retain card",
+	MerchantSuspicious:          "merchant suspicious",
+	ElectronicCashRegister:      "electronic cash register interface",
+	CustomerPresentCardNot:      "customer present, card not present",
+	PreAuthorized:               "preauthorized request",
+	TelephoneDevice:             "telephone device request",
+	MailTelephoneOrder:          "mail or telephone order",
+	SecurityAlert:               "security alert",
+	CustomerIdentityVerified:    "customer identity verified",
+	SuspectedFraud:              "suspected fraud",
+	SecurityReasons:             "security reasons",
+	RepresentationOfItem:        "representation of item",
+	PublicUtilityTerminal:       "public utility terminal",
+	CustomerTerminal:            "customer terminal",
+	AdministrativeTerminal:      "administrative terminal",
+	ReturnedItem:                "returned item",
+	NoChequeInEnvelope:          "no cheque in envelope returned",
+	DepositOutOfBalance:         "deposit out of balance",
+	PaymentOutOfBalance:         "payment out of balance",
+	ManualReversal:              "manual reversal",
+	TerminalErrorCounted:        "terminal error, counted",
+	TerminalErrorNotCounted:     "terminal error, not counted",
+	AccountVerificationRequest:  "account verification without authorization",
+	ElectronicCommerceCondition: "electronic commerce",
+}
+
+// String implements the fmt.Stringer interface.
+func (c POSCondition) String() string {
+	if s, ok := posConditions[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("%02d", uint8(c))
+}
+
+// ParsePOSCondition parses the point of service condition code of ISO 8583:1987 (n 2).
+func ParsePOSCondition(s string) (POSCondition, error) {
+	if len(s) != 2 || !digits(s) {
+		return 0, errors.Data
+	}
+	n, _ := strconv.Atoi(s)
+	return POSCondition(n), nil
+}
+
+// POSEntry returns the point of service entry of the message (field 22),
+// decoded as the entry mode of ISO 8583:1987 or as the data code of the next versions.
+// With the version of 1987, the PIN capture code (field 26) and the condition code (field 25) complete it.
+func (m *Message) POSEntry() (*POSEntry, error) {
+	if m.MTI == nil {
+		return nil, errors.MTI
+	}
+	f, ok := m.Data[22]
+	if !ok {
+		return nil, errors.New(errors.Data, 22)
+	}
+	if m.MTI.Version != V1987 {
+		e, err := ParsePOSDataCode(f.String())
+		if err != nil {
+			return nil, errors.New(err, 22)
+		}
+		return e, nil
+	}
+	e, err := ParsePOSEntryMode(f.String())
+	if err != nil {
+		return nil, errors.New(err, 22)
+	}
+	if f, ok = m.Data[26]; ok {
+		if n, err := strconv.Atoi(f.String()); err == nil && n >= 4 && n <= 12 {
+			e.PINLength = n
+		}
+	}
+	if c, err := m.POSCondition(); err == nil && e.Cardholder == UnknownPresence {
+		switch c {
+		case NormalPresentment, CustomerPresentCardNot:
+			e.Cardholder = CardholderPresent
+		case CustomerNotPresent:
+			e.Cardholder = CardholderNotPresent
+		case MailTelephoneOrder:
+			e.Cardholder = MailOrder
+		case ElectronicCommerceCondition:
+			e.Cardholder = ElectronicOrder
+		}
+	}
+	return e, nil
+}
+
+// POSCondition returns the point of service condition code of the message (field 25).
+// Since ISO 8583:1993, the field 25 is the message reason code: NotImplemented is returned.
+func (m *Message) POSCondition() (POSCondition, error) {
+	if m.MTI == nil {
+		return 0, errors.MTI
+	}
+	if m.MTI.Version != V1987 {
+		return 0, errors.New(errors.NotImplemented, 25)
+	}
+	f, ok := m.Data[25]
+	if !ok {
+		return 0, errors.New(errors.Data, 25)
+	}
+	c, err := ParsePOSCondition(f.String())
+	if err != nil {
+		return 0, errors.New(err, 25)
+	}
+	return c, nil
+}
+
+func digits(s string) bool {
+	for k := 0; k < len(s); k++ {
+		if s[k] < '0' || s[k] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestMessage_POSEntry(t *testing.T) {
+	posDataCode := field.Spec{22: {Format: field.Alpha | field.Numeric, Size: 12}}
+	for i, tt := range []struct {
+		mti    string
+		spec   field.Spec
+		values map[field.ID]string
+		out    *iso8583.POSEntry
+		err    bool
+	}{
+		{
+			mti:    "0100",
+			values: map[field.ID]string{22: "051", 26: "12"},
+			out:    &iso8583.POSEntry{Mode: iso8583.Chip, PIN: iso8583.PINCapable, PINLength: 12, Raw: "051"},
+		},
+		{
+			mti:    "0100",
+			values: map[field.ID]string{22: "072", 25: "00"},
+			out: &iso8583.POSEntry{
+				Mode: iso8583.Contactless, PIN: iso8583.NoPINCapability, Cardholder: iso8583.CardholderPresent, Raw: "072",
+			},
+		},
+		{
+			mti:    "0200",
+			values: map[field.ID]string{22: "812", 25: "08"},
+			out: &iso8583.POSEntry{
+				Mode: iso8583.ECommerce, PIN: iso8583.NoPINCapability, Cardholder: iso8583.ElectronicOrder, Raw: "812",
+			},
+		},
+		{
+			mti:    "0100",
+			values: map[field.ID]string{22: "010", 25: "08"},
+			out:    &iso8583.POSEntry{Mode: iso8583.ManualEntry, Cardholder: iso8583.MailOrder, Raw: "010"},
+		},
+		{
+			mti:    "1100",
+			spec:   posDataCode,
+			values: map[field.ID]string{22: "510101513146"},
+			out: &iso8583.POSEntry{
+				Mode: iso8583.Chip, PIN: iso8583.PINCapable, Cardholder: iso8583.CardholderPresent, PINLength: 6, Raw: "510101513146",
+			},
+		},
+		{
+			mti:    "2100",
+			spec:   posDataCode,
+			values: map[field.ID]string{22: "100050600001"},
+			out: &iso8583.POSEntry{
+				Mode: iso8583.ECommerce, PIN: iso8583.NoPINCapability, Cardholder: iso8583.ElectronicOrder, Raw: "100050600001",
+			},
+		},
+		{mti: "0100", err: true},
+		{mti: "0100", values: map[field.ID]string{22: "5101"}, err: true},
+		{mti: "1100", values: map[field.ID]string{22: "051"}, err: true},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			m := newMessage(tt.mti, nil)
+			m.Spec = tt.spec
+			for id, v := range tt.values {
+				f := m.Spec.New(id)
+				f.Value = []byte(v)
+				m.Data[id] = f
+			}
+			out, err := m.POSEntry()
+			are := is.New(t)
+			are.Equal(err != nil, tt.err)
+			are.Equal(out, tt.out)
+		})
+	}
+}
+
+func TestMessage_POSCondition(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = newMessage("0100", map[field.ID]string{25: "08"})
+	)
+	c, err := m.POSCondition()
+	are.NoErr(err)
+	are.Equal(c, iso8583.MailTelephoneOrder)
+	are.Equal(c.String(), "mail or telephone order")
+	are.Equal(iso8583.POSCondition(42).String(), "42")
+
+	// Since 1993, the field 25 is the message reason code.
+	m = newMessage("1100", map[field.ID]string{25: "08"})
+	_, err = m.POSCondition()
+	are.Equal(err.Error(), errors.New(errors.NotImplemented, 25).Error())
+
+	_, err = iso8583.ParsePOSCondition("A1")
+	are.Equal(err, errors.Data)
+}
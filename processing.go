@@ -0,0 +1,229 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583
+
+import (
+	"fmt"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// TransactionType is the type of transaction, the first two digits of the processing code.
+type TransactionType uint8
+
+// List of transaction types.
+const (
+	// Purchase of goods and services, aka 00.
+	Purchase TransactionType = 0
+	// CashWithdrawal aka 01.
+	CashWithdrawal TransactionType = 1
+	// DebitAdjustment aka 02.
+	DebitAdjustment TransactionType = 2
+	// ChequeGuarantee aka 03.
+	ChequeGuarantee TransactionType = 3
+	// ChequeVerification aka 04.
+	ChequeVerification TransactionType = 4
+	// Eurocheque aka 05.
+	Eurocheque TransactionType = 5
+	// TravellerCheque aka 06.
+	TravellerCheque TransactionType = 6
+	// LetterOfCredit aka 07.
+	LetterOfCredit TransactionType = 7
+	// Giro aka 08.
+	Giro TransactionType = 8
+	// PurchaseWithCashback is a purchase of goods and services with cash disbursement, aka 09.
+	PurchaseWithCashback TransactionType = 9
+	// QuasiCash aka 11.
+	QuasiCash TransactionType = 11
+	// Refund is the return of goods and services, aka 20.
+	Refund TransactionType = 20
+	// Deposit aka 21.
+	Deposit TransactionType = 21
+	// CreditAdjustment aka 22.
+	CreditAdjustment TransactionType = 22
+	// ChequeDepositGuarantee aka 23.
+	ChequeDepositGuarantee TransactionType = 23
+	// ChequeDeposit aka 24.
+	ChequeDeposit TransactionType = 24
+	// AvailableFundsInquiry aka 30.
+	AvailableFundsInquiry TransactionType = 30
+	// BalanceInquiry aka 31.
+	BalanceInquiry TransactionType = 31
+	// Transfer between the cardholder accounts, aka 40.
+	Transfer TransactionType = 40
+)
+
+// String implements the fmt.Stringer interface.
+func (t TransactionType) String() string {
+	if s, ok := TransactionTypes[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("%02d", uint8(t))
+}
+
+// AccountType is the type of account debited or credited, the next two pairs of digits of the processing code.
+type AccountType uint8
+
+// List of account types.
+const (
+	// DefaultAccount is the unspecified account, aka 00.
+	DefaultAccount AccountType = 0
+	// SavingsAccount aka 10.
+	SavingsAccount AccountType = 10
+	// CheckingAccount aka 20.
+	CheckingAccount AccountType = 20
+	// CreditAccount aka 30.
+	CreditAccount AccountType = 30
+	// UniversalAccount aka 40.
+	UniversalAccount AccountType = 40
+	// InvestmentAccount aka 50.
+	InvestmentAccount AccountType = 50
+)
+
+var accountTypes = map[AccountType]string{
+	DefaultAccount:    "default",
+	SavingsAccount:    "savings",
+	CheckingAccount:   "checking",
+	CreditAccount:     "credit",
+	UniversalAccount:  "universal",
+	InvestmentAccount: "investment",
+}
+
+// String implements the fmt.Stringer interface.
+func (a AccountType) String() string {
+	if s, ok := accountTypes[a]; ok {
+		return s
+	}
+	return fmt.Sprintf("%02d", uint8(a))
+}
+
+// TransactionTypeTable names the known transaction types.
+type TransactionTypeTable map[TransactionType]string
+
+// TransactionTypes is the table of the transaction types of ISO 8583:1987.
+var TransactionTypes = TransactionTypeTable{
+	Purchase:               "purchase",
+	CashWithdrawal:         "cash withdrawal",
+	DebitAdjustment:        "debit adjustment",
+	ChequeGuarantee:        "cheque guarantee",
+	ChequeVerification:     "cheque verification",
+	Eurocheque:             "eurocheque",
+	TravellerCheque:        "traveller cheque",
+	LetterOfCredit:         "letter of credit",
+	Giro:                   "giro",
+	PurchaseWithCashback:   "purchase with cashback",
+	QuasiCash:              "quasi-cash",
+	Refund:                 "refund",
+	Deposit:                "deposit",
+	CreditAdjustment:       "credit adjustment",
+	ChequeDepositGuarantee: "cheque deposit guarantee",
+	ChequeDeposit:          "cheque deposit",
+	AvailableFundsInquiry:  "available funds inquiry",
+	BalanceInquiry:         "balance inquiry",
+	Transfer:               "transfer",
+}
+
+// Extend returns a copy of the table with these transaction types, added or renamed.
+// It allows a dialect to define its own transaction types.
+func (t TransactionTypeTable) Extend(types map[TransactionType]string) TransactionTypeTable {
+	out := make(TransactionTypeTable, len(t)+len(types))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range types {
+		out[k] = v
+	}
+	return out
+}
+
+// Parse parses the processing code and checks that its transaction type is in the table.
+func (t TransactionTypeTable) Parse(s string) (*ProcessingCode, error) {
+	p, err := ParseProcessingCode(s)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t[p.Type]; !ok {
+		return nil, errors.NotImplemented
+	}
+	return p, nil
+}
+
+// ParseProcessingCode parses a string to extract the processing code, whatever its transaction type.
+// See TransactionTypeTable.Parse to only accept the known transaction types.
+func ParseProcessingCode(s string) (*ProcessingCode, error) {
+	if len(s) != 6 {
+		return nil, errors.Data
+	}
+	var d [3]uint8
+	for k := 0; k < len(s); k++ {
+		if s[k] < '0' || s[k] > '9' {
+			return nil, errors.Data
+		}
+		d[k/2] = d[k/2]*10 + s[k] - '0'
+	}
+	return NewProcessingCode(TransactionType(d[0]), AccountType(d[1]), AccountType(d[2])), nil
+}
+
+// NewProcessingCode returns a new processing code.
+func NewProcessingCode(t TransactionType, from, to AccountType) *ProcessingCode {
+	return &ProcessingCode{Type: t, From: from, To: to}
+}
+
+// ProcessingCode is the field 3: the type of transaction and the accounts affected.
+type ProcessingCode struct {
+	Type TransactionType
+	From AccountType
+	To   AccountType
+}
+
+// String implements the fmt.Stringer interface.
+func (p *ProcessingCode) String() string {
+	return fmt.Sprintf("%02d%02d%02d", p.Type, p.From, p.To)
+}
+
+// Valid returns in success if each part of the processing code has two digits.
+func (p *ProcessingCode) Valid() bool {
+	return p.Type < 100 && p.From < 100 && p.To < 100
+}
+
+// ProcessingCode returns the processing code of the message, whatever its transaction type.
+func (m *Message) ProcessingCode() (*ProcessingCode, error) {
+	return m.processingCode(ParseProcessingCode)
+}
+
+// ProcessingCodeIn returns the processing code of the message, with a transaction type of this table.
+func (m *Message) ProcessingCodeIn(t TransactionTypeTable) (*ProcessingCode, error) {
+	return m.processingCode(t.Parse)
+}
+
+func (m *Message) processingCode(parse func(s string) (*ProcessingCode, error)) (*ProcessingCode, error) {
+	f, ok := m.Data[3]
+	if !ok {
+		return nil, errors.New(errors.Data, 3)
+	}
+	p, err := parse(f.String())
+	if err != nil {
+		return nil, errors.New(err, 3)
+	}
+	return p, nil
+}
+
+// SetProcessingCode sets the processing code of the message.
+func (m *Message) SetProcessingCode(p *ProcessingCode) error {
+	if p == nil || !p.Valid() {
+		return errors.New(errors.Data, 3)
+	}
+	f := m.newData(3)
+	f.Value = []byte(p.String())
+	if _, err := field.Marshal(f); err != nil {
+		return errors.New(err, 3)
+	}
+	if m.Data == nil {
+		m.Data = Fields{}
+	}
+	m.Data[3] = f
+	return nil
+}
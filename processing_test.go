@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package iso8583_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+func TestParseProcessingCode(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		out *iso8583.ProcessingCode
+		err error
+	}{
+		{in: "000000", out: iso8583.NewProcessingCode(iso8583.Purchase, iso8583.DefaultAccount, iso8583.DefaultAccount)},
+		{in: "011000", out: iso8583.NewProcessingCode(iso8583.CashWithdrawal, iso8583.SavingsAccount, iso8583.DefaultAccount)},
+		{in: "200030", out: iso8583.NewProcessingCode(iso8583.Refund, iso8583.DefaultAccount, iso8583.CreditAccount)},
+		{in: "312000", out: iso8583.NewProcessingCode(iso8583.BalanceInquiry, iso8583.CheckingAccount, iso8583.DefaultAccount)},
+		{in: "401020", out: iso8583.NewProcessingCode(iso8583.Transfer, iso8583.SavingsAccount, iso8583.CheckingAccount)},
+		{in: "910000", out: iso8583.NewProcessingCode(91, iso8583.DefaultAccount, iso8583.DefaultAccount)},
+		{in: "00000", err: errors.Data},
+		{in: "00A000", err: errors.Data},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := iso8583.ParseProcessingCode(tt.in)
+			are := is.New(t)
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+			if err == nil {
+				are.Equal(out.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestTransactionTypeTable_Extend(t *testing.T) {
+	var (
+		are    = is.New(t)
+		change = iso8583.TransactionType(92)
+		table  = iso8583.TransactionTypes.Extend(map[iso8583.TransactionType]string{change: "PIN change"})
+	)
+	p, err := table.Parse("920000")
+	are.NoErr(err)
+	are.Equal(p.Type, change)
+	are.Equal(table[p.Type], "PIN change")
+	// The table of the standard is unchanged.
+	_, err = iso8583.TransactionTypes.Parse("920000")
+	are.Equal(err, errors.NotImplemented)
+	_, err = iso8583.TransactionTypes.Parse("92000A")
+	are.Equal(err, errors.Data)
+	are.Equal(change.String(), "92")
+	are.Equal(iso8583.PurchaseWithCashback.String(), "purchase with cashback")
+	are.Equal(iso8583.UniversalAccount.String(), "universal")
+}
+
+func TestMessage_ProcessingCode(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = &iso8583.Message{MTI: iso8583.NewMTI(iso8583.V1987, iso8583.Financial)}
+	)
+	_, err := m.ProcessingCode()
+	are.True(err != nil)
+	are.NoErr(m.SetProcessingCode(iso8583.NewProcessingCode(iso8583.Refund, iso8583.DefaultAccount, iso8583.CheckingAccount)))
+	are.Equal(m.Data[3].String(), "200020")
+	p, err := m.ProcessingCode()
+	are.NoErr(err)
+	are.Equal(p.Type, iso8583.Refund)
+	are.Equal(p.To, iso8583.CheckingAccount)
+	are.True(m.SetProcessingCode(iso8583.NewProcessingCode(100, 0, 0)) != nil)
+
+	// Transaction types out of the table of the standard.
+	are.NoErr(m.SetProcessingCode(iso8583.NewProcessingCode(26, iso8583.DefaultAccount, iso8583.DefaultAccount)))
+	p, err = m.ProcessingCode()
+	are.NoErr(err)
+	are.Equal(p.Type, iso8583.TransactionType(26))
+	_, err = m.ProcessingCodeIn(iso8583.TransactionTypes)
+	are.Equal(err, errors.New(errors.NotImplemented, 3))
+	p, err = m.ProcessingCodeIn(iso8583.TransactionTypes.Extend(map[iso8583.TransactionType]string{26: "original credit"}))
+	are.NoErr(err)
+	are.Equal(p.String(), "260000")
+
+	// A dialect using an alphanumeric processing code.
+	m.Spec = field.Spec{3: {Format: field.Alpha, Size: 6}}
+	are.True(m.SetProcessingCode(iso8583.NewProcessingCode(iso8583.Purchase, 0, 0)) != nil)
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package response
+
+// ISO1987 lists the two-character response codes of ISO 8583:1987.
+var ISO1987 = Catalogue{
+	"00": {Value: "00", Action: Approve, Description: "Approved or completed successfully"},
+	"01": {Value: "01", Action: Refer, Description: "Refer to card issuer"},
+	"02": {Value: "02", Action: Refer, Description: "Refer to card issuer, special condition"},
+	"03": {Value: "03", Action: Decline, Description: "Invalid merchant"},
+	"04": {Value: "04", Action: PickUp, Description: "Pick-up card"},
+	"05": {Value: "05", Action: Decline, Description: "Do not honour"},
+	"06": {Value: "06", Action: Decline, Description: "Error"},
+	"07": {Value: "07", Action: PickUp, Description: "Pick-up card, special condition"},
+	"08": {Value: "08", Action: Approve, Description: "Honour with identification"},
+	"09": {Value: "09", Action: Retry, Description: "Request in progress"},
+	"10": {Value: "10", Action: Approve, Description: "Approved for partial amount"},
+	"11": {Value: "11", Action: Approve, Description: "Approved (VIP)"},
+	"12": {Value: "12", Action: Decline, Description: "Invalid transaction"},
+	"13": {Value: "13", Action: Decline, Description: "Invalid amount"},
+	"14": {Value: "14", Action: Decline, Description: "Invalid card number"},
+	"15": {Value: "15", Action: Decline, Description: "No such issuer"},
+	"16": {Value: "16", Action: Approve, Description: "Approved, update track 3"},
+	"17": {Value: "17", Action: Decline, Description: "Customer cancellation"},
+	"18": {Value: "18", Action: Decline, Description: "Customer dispute"},
+	"19": {Value: "19", Action: Retry, Description: "Re-enter transaction"},
+	"20": {Value: "20", Action: Decline, Description: "Invalid response"},
+	"21": {Value: "21", Action: Decline, Description: "No action taken"},
+	"22": {Value: "22", Action: Retry, Description: "Suspected malfunction"},
+	"23": {Value: "23", Action: Decline, Description: "Unacceptable transaction fee"},
+	"24": {Value: "24", Action: Decline, Description: "File update not supported by receiver"},
+	"25": {Value: "25", Action: Decline, Description: "Unable to locate record on file"},
+	"26": {Value: "26", Action: Decline, Description: "Duplicate file update record, old record replaced"},
+	"27": {Value: "27", Action: Decline, Description: "File update field edit error"},
+	"28": {Value: "28", Action: Retry, Description: "File update file locked out"},
+	"29": {Value: "29", Action: Decline, Description: "File update not successful, contact acquirer"},
+	"30": {Value: "30", Action: Decline, Description: "Format error"},
+	"31": {Value: "31", Action: Decline, Description: "Bank not supported by switch"},
+	"32": {Value: "32", Action: Approve, Description: "Completed partially"},
+	"33": {Value: "33", Action: PickUp, Description: "Expired card, pick-up"},
+	"34": {Value: "34", Action: PickUp, Description: "Suspected fraud, pick-up"},
+	"35": {Value: "35", Action: PickUp, Description: "Card acceptor contact acquirer, pick-up"},
+	"36": {Value: "36", Action: PickUp, Description: "Restricted card, pick-up"},
+	"37": {Value: "37", Action: PickUp, Description: "Card acceptor call acquirer security, pick-up"},
+	"38": {Value: "38", Action: PickUp, Description: "Allowable PIN tries exceeded, pick-up"},
+	"39": {Value: "39", Action: Decline, Description: "No credit account"},
+	"40": {Value: "40", Action: Decline, Description: "Requested function not supported"},
+	"41": {Value: "41", Action: PickUp, Description: "Lost card, pick-up"},
+	"42": {Value: "42", Action: Decline, Description: "No universal account"},
+	"43": {Value: "43", Action: PickUp, Description: "Stolen card, pick-up"},
+	"44": {Value: "44", Action: Decline, Description: "No investment account"},
+	"51": {Value: "51", Action: Decline, Description: "Not sufficient funds"},
+	"52": {Value: "52", Action: Decline, Description: "No cheque account"},
+	"53": {Value: "53", Action: Decline, Description: "No savings account"},
+	"54": {Value: "54", Action: Decline, Description: "Expired card"},
+	"55": {Value: "55", Action: Decline, Description: "Incorrect PIN"},
+	"56": {Value: "56", Action: Decline, Description: "No card record"},
+	"57": {Value: "57", Action: Decline, Description: "Transaction not permitted to cardholder"},
+	"58": {Value: "58", Action: Decline, Description: "Transaction not permitted to terminal"},
+	"59": {Value: "59", Action: Decline, Description: "Suspected fraud"},
+	"60": {Value: "60", Action: Decline, Description: "Card acceptor contact acquirer"},
+	"61": {Value: "61", Action: Decline, Description: "Exceeds withdrawal amount limit"},
+	"62": {Value: "62", Action: Decline, Description: "Restricted card"},
+	"63": {Value: "63", Action: Decline, Description: "Security violation"},
+	"64": {Value: "64", Action: Decline, Description: "Original amount incorrect"},
+	"65": {Value: "65", Action: Decline, Description: "Exceeds withdrawal frequency limit"},
+	"66": {Value: "66", Action: Decline, Description: "Card acceptor call acquirer's security department"},
+	"67": {Value: "67", Action: PickUp, Description: "Hard capture, pick-up card at ATM"},
+	"68": {Value: "68", Action: Retry, Description: "Response received too late"},
+	"75": {Value: "75", Action: Decline, Description: "Allowable number of PIN tries exceeded"},
+	"90": {Value: "90", Action: Retry, Description: "Cutoff is in process"},
+	"91": {Value: "91", Action: Retry, Description: "Issuer or switch is inoperative"},
+	"92": {Value: "92", Action: Retry, Description: "Financial institution or intermediate network facility cannot be found for routing"},
+	"93": {Value: "93", Action: Decline, Description: "Transaction cannot be completed, violation of law"},
+	"94": {Value: "94", Action: Decline, Description: "Duplicate transmission"},
+	"95": {Value: "95", Action: Decline, Description: "Reconcile error"},
+	"96": {Value: "96", Action: Retry, Description: "System malfunction"},
+}
+
+// ISO1993 lists the three-digit action codes of ISO 8583:1993.
+var ISO1993 = Catalogue{
+	"000": {Value: "000", Action: Approve, Description: "Approved"},
+	"001": {Value: "001", Action: Approve, Description: "Honour with identification"},
+	"002": {Value: "002", Action: Approve, Description: "Approved for partial amount"},
+	"003": {Value: "003", Action: Approve, Description: "Approved (VIP)"},
+	"004": {Value: "004", Action: Approve, Description: "Approved, update track 3"},
+	"005": {Value: "005", Action: Approve, Description: "Approved, account type specified by card issuer"},
+	"006": {Value: "006", Action: Approve, Description: "Approved for partial amount, account type specified by card issuer"},
+	"007": {Value: "007", Action: Approve, Description: "Approved, update ICC"},
+	"100": {Value: "100", Action: Decline, Description: "Do not honour"},
+	"101": {Value: "101", Action: Decline, Description: "Expired card"},
+	"102": {Value: "102", Action: Decline, Description: "Suspected fraud"},
+	"103": {Value: "103", Action: Decline, Description: "Card acceptor contact acquirer"},
+	"104": {Value: "104", Action: Decline, Description: "Restricted card"},
+	"105": {Value: "105", Action: Decline, Description: "Card acceptor call acquirer's security department"},
+	"106": {Value: "106", Action: Decline, Description: "Allowable PIN tries exceeded"},
+	"107": {Value: "107", Action: Refer, Description: "Refer to card issuer"},
+	"108": {Value: "108", Action: Refer, Description: "Refer to card issuer's special conditions"},
+	"109": {Value: "109", Action: Decline, Description: "Invalid merchant"},
+	"110": {Value: "110", Action: Decline, Description: "Invalid amount"},
+	"111": {Value: "111", Action: Decline, Description: "Invalid card number"},
+	"112": {Value: "112", Action: Decline, Description: "PIN data required"},
+	"113": {Value: "113", Action: Decline, Description: "Unacceptable fee"},
+	"114": {Value: "114", Action: Decline, Description: "No account of type requested"},
+	"115": {Value: "115", Action: Decline, Description: "Requested function not supported"},
+	"116": {Value: "116", Action: Decline, Description: "Not sufficient funds"},
+	"117": {Value: "117", Action: Decline, Description: "Incorrect PIN"},
+	"118": {Value: "118", Action: Decline, Description: "No card record"},
+	"119": {Value: "119", Action: Decline, Description: "Transaction not permitted to cardholder"},
+	"120": {Value: "120", Action: Decline, Description: "Transaction not permitted to terminal"},
+	"121": {Value: "121", Action: Decline, Description: "Exceeds withdrawal amount limit"},
+	"122": {Value: "122", Action: Decline, Description: "Security violation"},
+	"123": {Value: "123", Action: Decline, Description: "Exceeds withdrawal frequency limit"},
+	"124": {Value: "124", Action: Decline, Description: "Violation of law"},
+	"125": {Value: "125", Action: Decline, Description: "Card not effective"},
+	"126": {Value: "126", Action: Decline, Description: "Invalid PIN block"},
+	"127": {Value: "127", Action: Decline, Description: "PIN length error"},
+	"128": {Value: "128", Action: Decline, Description: "PIN key synch error"},
+	"129": {Value: "129", Action: Decline, Description: "Suspected counterfeit card"},
+	"200": {Value: "200", Action: PickUp, Description: "Do not honour"},
+	"201": {Value: "201", Action: PickUp, Description: "Expired card"},
+	"202": {Value: "202", Action: PickUp, Description: "Suspected fraud"},
+	"203": {Value: "203", Action: PickUp, Description: "Card acceptor contact acquirer"},
+	"204": {Value: "204", Action: PickUp, Description: "Restricted card"},
+	"205": {Value: "205", Action: PickUp, Description: "Card acceptor call acquirer's security department"},
+	"206": {Value: "206", Action: PickUp, Description: "Allowable PIN tries exceeded"},
+	"207": {Value: "207", Action: PickUp, Description: "Special conditions"},
+	"208": {Value: "208", Action: PickUp, Description: "Lost card"},
+	"209": {Value: "209", Action: PickUp, Description: "Stolen card"},
+	"210": {Value: "210", Action: PickUp, Description: "Suspected counterfeit card"},
+	"300": {Value: "300", Action: Approve, Description: "Successful file action"},
+	"301": {Value: "301", Action: Decline, Description: "Not supported by receiver"},
+	"302": {Value: "302", Action: Decline, Description: "Unable to locate record on file"},
+	"303": {Value: "303", Action: Approve, Description: "Duplicate record, old record replaced"},
+	"304": {Value: "304", Action: Decline, Description: "Field edit error"},
+	"305": {Value: "305", Action: Retry, Description: "File locked out"},
+	"306": {Value: "306", Action: Decline, Description: "Not successful"},
+	"307": {Value: "307", Action: Decline, Description: "Format error"},
+	"308": {Value: "308", Action: Decline, Description: "Duplicate, new record rejected"},
+	"309": {Value: "309", Action: Decline, Description: "Unknown file"},
+	"400": {Value: "400", Action: Approve, Description: "Accepted"},
+	"500": {Value: "500", Action: Approve, Description: "Reconciled, in balance"},
+	"501": {Value: "501", Action: Decline, Description: "Reconciled, out of balance"},
+	"502": {Value: "502", Action: Decline, Description: "Amount not reconciled, totals provided"},
+	"503": {Value: "503", Action: Decline, Description: "Totals for reconciliation not available"},
+	"504": {Value: "504", Action: Decline, Description: "Not reconciled, totals provided"},
+	"600": {Value: "600", Action: Approve, Description: "Accepted"},
+	"601": {Value: "601", Action: Decline, Description: "Not able to trace back original transaction"},
+	"602": {Value: "602", Action: Decline, Description: "Invalid reference number"},
+	"603": {Value: "603", Action: Decline, Description: "Reference number or PAN incompatible"},
+	"604": {Value: "604", Action: Decline, Description: "POS photograph is not available"},
+	"605": {Value: "605", Action: Approve, Description: "Item supplied"},
+	"606": {Value: "606", Action: Decline, Description: "Request cannot be fulfilled, required documentation is not available"},
+	"700": {Value: "700", Action: Approve, Description: "Accepted"},
+	"800": {Value: "800", Action: Approve, Description: "Accepted"},
+	"900": {Value: "900", Action: Approve, Description: "Advice acknowledged, no financial liability accepted"},
+	"901": {Value: "901", Action: Approve, Description: "Advice acknowledged, financial liability accepted"},
+	"902": {Value: "902", Action: Decline, Description: "Invalid transaction"},
+	"903": {Value: "903", Action: Retry, Description: "Re-enter transaction"},
+	"904": {Value: "904", Action: Decline, Description: "Format error"},
+	"905": {Value: "905", Action: Decline, Description: "Acquirer not supported by switch"},
+	"906": {Value: "906", Action: Retry, Description: "Cutover in process"},
+	"907": {Value: "907", Action: Retry, Description: "Card issuer or switch inoperative"},
+	"908": {Value: "908", Action: Retry, Description: "Transaction destination cannot be found for routing"},
+	"909": {Value: "909", Action: Retry, Description: "System malfunction"},
+	"910": {Value: "910", Action: Retry, Description: "Card issuer signed off"},
+	"911": {Value: "911", Action: Retry, Description: "Card issuer timed out"},
+	"912": {Value: "912", Action: Retry, Description: "Card issuer unavailable"},
+	"913": {Value: "913", Action: Decline, Description: "Duplicate transmission"},
+	"914": {Value: "914", Action: Decline, Description: "Not able to trace back to original transaction"},
+	"915": {Value: "915", Action: Retry, Description: "Reconciliation cutover or checkpoint error"},
+	"916": {Value: "916", Action: Decline, Description: "MAC incorrect"},
+	"917": {Value: "917", Action: Retry, Description: "MAC key sync error"},
+	"918": {Value: "918", Action: Retry, Description: "No communication keys available for use"},
+	"919": {Value: "919", Action: Retry, Description: "Encryption key sync error"},
+	"920": {Value: "920", Action: Retry, Description: "Security software error, try again"},
+	"921": {Value: "921", Action: Decline, Description: "Security software error, no action"},
+	"922": {Value: "922", Action: Decline, Description: "Message number out of sequence"},
+	"923": {Value: "923", Action: Retry, Description: "Request in progress"},
+}
+
+// ISO2003 lists the four-digit action codes of ISO 8583:2003:
+// the codes of ISO 8583:1993 followed by the sub-code 0.
+var ISO2003 = func() Catalogue {
+	c := make(Catalogue, len(ISO1993))
+	for k, v := range ISO1993 {
+		v.Value = k + "0"
+		c[v.Value] = v
+	}
+	return c
+}()
+
+// equivalents lists the pairs of equivalent codes between ISO 8583:1987 and 1993.
+// The first pair of a code is used to convert it.
+var equivalents = [][2]string{
+	{"00", "000"}, {"01", "107"}, {"02", "108"}, {"03", "109"}, {"04", "200"}, {"05", "100"},
+	{"06", "909"}, {"07", "207"}, {"08", "001"}, {"09", "923"}, {"10", "002"}, {"11", "003"},
+	{"12", "902"}, {"13", "110"}, {"14", "111"}, {"15", "908"}, {"16", "004"}, {"19", "903"},
+	{"23", "113"}, {"24", "301"}, {"25", "302"}, {"26", "303"}, {"27", "304"}, {"28", "305"},
+	{"29", "306"}, {"30", "904"}, {"31", "905"}, {"33", "201"}, {"34", "202"}, {"35", "203"},
+	{"36", "204"}, {"37", "205"}, {"38", "206"}, {"39", "114"}, {"40", "115"}, {"41", "208"},
+	{"42", "114"}, {"43", "209"}, {"44", "114"}, {"51", "116"}, {"52", "114"}, {"53", "114"},
+	{"54", "101"}, {"55", "117"}, {"56", "118"}, {"57", "119"}, {"58", "120"}, {"59", "102"},
+	{"60", "103"}, {"61", "121"}, {"62", "104"}, {"63", "122"}, {"65", "123"}, {"66", "105"},
+	{"75", "106"}, {"90", "906"}, {"91", "907"}, {"92", "908"}, {"93", "124"}, {"94", "913"},
+	{"95", "915"}, {"96", "909"},
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package response classifies the response codes of ISO 8583:1987
+// and the action codes of ISO 8583:1993 and 2003, carried by the field 39.
+package response
+
+import (
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+)
+
+// Field is the position of the response code.
+const Field field.ID = 39
+
+// Action is the class of a response code.
+type Action uint8
+
+// List of classes.
+const (
+	// Unknown is the class of the codes missing from the catalogue.
+	Unknown Action = iota
+	// Approve means the request is approved, even partially.
+	Approve
+	// Decline means the request is declined.
+	Decline
+	// Refer means the card acceptor must refer to the card issuer.
+	Refer
+	// PickUp means the request is declined and the card must be retained.
+	PickUp
+	// Retry means the request has not been processed and can be sent again later.
+	Retry
+)
+
+var actions = [...]string{"unknown", "approve", "decline", "refer", "pick-up", "retry"}
+
+// String implements the fmt.Stringer interface.
+func (a Action) String() string {
+	if int(a) < len(actions) {
+		return actions[a]
+	}
+	return actions[Unknown]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (a *Action) UnmarshalText(text []byte) error {
+	for k, v := range actions {
+		if v == string(text) {
+			*a = Action(k)
+			return nil
+		}
+	}
+	return errors.Data
+}
+
+// Code is a response code with its class and meaning.
+type Code struct {
+	Value       string `json:"value"`
+	Action      Action `json:"action"`
+	Description string `json:"description"`
+}
+
+// String implements the fmt.Stringer interface.
+func (c Code) String() string {
+	if c.Description == "" {
+		return c.Value
+	}
+	return c.Value + " " + c.Description
+}
+
+// Catalogue lists the known codes by value.
+type Catalogue map[string]Code
+
+// For returns the catalogue of the version of the standard.
+func For(v iso8583.Version) Catalogue {
+	switch v {
+	case iso8583.V1987:
+		return ISO1987
+	case iso8583.V1993:
+		return ISO1993
+	case iso8583.V2003:
+		return ISO2003
+	default:
+		return nil
+	}
+}
+
+// Extend returns a copy of the catalogue with these codes, added or overridden.
+// It allows a dialect to define its own codes.
+func (c Catalogue) Extend(codes ...Code) Catalogue {
+	out := make(Catalogue, len(c)+len(codes))
+	for k, v := range c {
+		out[k] = v
+	}
+	for _, v := range codes {
+		out[v.Value] = v
+	}
+	return out
+}
+
+// Lookup returns the code with this value. If it is not in the catalogue, its class is Unknown.
+func (c Catalogue) Lookup(value string) Code {
+	if v, ok := c[value]; ok {
+		return v
+	}
+	return Code{Value: value}
+}
+
+// Get returns the response code of the message, looked up in the catalogue.
+func (c Catalogue) Get(m *iso8583.Message) (Code, error) {
+	f, ok := m.Data[Field]
+	if !ok {
+		return Code{}, errors.New(errors.Data, int(Field))
+	}
+	return c.Lookup(f.String()), nil
+}
+
+// Get returns the response code of the message, looked up in the catalogue of its version.
+func Get(m *iso8583.Message) (Code, error) {
+	if m.MTI == nil {
+		return Code{}, errors.MTI
+	}
+	return For(m.MTI.Version).Get(m)
+}
+
+// Set sets the response code of the message, converted to the scheme of its version if needed.
+func Set(m *iso8583.Message, value string) error {
+	if m.MTI == nil {
+		return errors.MTI
+	}
+	from, ok := scheme(value)
+	if !ok {
+		return errors.New(errors.Data, int(Field))
+	}
+	value, err := Convert(value, from, m.MTI.Version)
+	if err != nil {
+		return errors.New(err, int(Field))
+	}
+	f := &field.Data{Element: m.Element(Field), Pos: Field}
+	f.Value = []byte(value)
+	if _, err = field.Marshal(f); err != nil {
+		return errors.New(err, int(Field))
+	}
+	if m.Data == nil {
+		m.Data = iso8583.Fields{}
+	}
+	m.Data[Field] = f
+	return nil
+}
+
+// Convert returns the equivalent code in the scheme of another version of the standard.
+// The sub-code of a code of ISO 8583:2003 is lost.
+func Convert(value string, from, to iso8583.Version) (string, error) {
+	if v, ok := scheme(value); !ok || v != from || !to.Valid() {
+		return "", errors.Data
+	}
+	if from == to {
+		return value, nil
+	}
+	var code string
+	switch from {
+	case iso8583.V1987:
+		v, ok := forward[value]
+		if !ok {
+			return "", errors.NotImplemented
+		}
+		code = v
+	case iso8583.V1993:
+		code = value
+	case iso8583.V2003:
+		code = value[:3]
+	}
+	// The code is now in the scheme of 1993.
+	switch to {
+	case iso8583.V1987:
+		v, ok := reverse[code]
+		if !ok {
+			return "", errors.NotImplemented
+		}
+		return v, nil
+	case iso8583.V2003:
+		return code + "0", nil
+	default:
+		return code, nil
+	}
+}
+
+// scheme returns the version of the standard using codes of this length.
+func scheme(value string) (iso8583.Version, bool) {
+	switch len(value) {
+	case 2:
+		return iso8583.V1987, true
+	case 3:
+		return iso8583.V1993, true
+	case 4:
+		return iso8583.V2003, true
+	default:
+		return 0, false
+	}
+}
+
+var forward, reverse = func() (map[string]string, map[string]string) {
+	var (
+		f = make(map[string]string, len(equivalents))
+		r = make(map[string]string, len(equivalents))
+	)
+	for _, v := range equivalents {
+		if _, ok := f[v[0]]; !ok {
+			f[v[0]] = v[1]
+		}
+		if _, ok := r[v[1]]; !ok {
+			r[v[1]] = v[0]
+		}
+	}
+	return f, r
+}()
@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package response_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/response"
+)
+
+func TestCatalogue_Lookup(t *testing.T) {
+	for i, tt := range []struct {
+		version iso8583.Version
+		in      string
+		out     response.Action
+	}{
+		{version: iso8583.V1987, in: "00", out: response.Approve},
+		{version: iso8583.V1987, in: "01", out: response.Refer},
+		{version: iso8583.V1987, in: "05", out: response.Decline},
+		{version: iso8583.V1987, in: "43", out: response.PickUp},
+		{version: iso8583.V1987, in: "91", out: response.Retry},
+		{version: iso8583.V1987, in: "Z3", out: response.Unknown},
+		{version: iso8583.V1993, in: "000", out: response.Approve},
+		{version: iso8583.V1993, in: "116", out: response.Decline},
+		{version: iso8583.V1993, in: "209", out: response.PickUp},
+		{version: iso8583.V1993, in: "911", out: response.Retry},
+		{version: iso8583.V2003, in: "1160", out: response.Decline},
+		{version: iso8583.V2003, in: "116", out: response.Unknown},
+		{version: 9, in: "00", out: response.Unknown},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			c := response.For(tt.version).Lookup(tt.in)
+			are := is.New(t)
+			are.Equal(c.Value, tt.in)
+			are.Equal(c.Action, tt.out)
+		})
+	}
+}
+
+func TestCatalogue_Extend(t *testing.T) {
+	var (
+		are = is.New(t)
+		c   = response.ISO1987.Extend(response.Code{Value: "Z3", Action: response.Decline, Description: "Offline declined"})
+	)
+	are.Equal(c.Lookup("Z3").Action, response.Decline)
+	are.Equal(c.Lookup("00").Action, response.Approve)
+	// The catalogue of the standard is unchanged.
+	are.Equal(response.ISO1987.Lookup("Z3").Action, response.Unknown)
+	are.Equal(c.Lookup("Z3").String(), "Z3 Offline declined")
+}
+
+func TestConvert(t *testing.T) {
+	for i, tt := range []struct {
+		in       string
+		from, to iso8583.Version
+		out      string
+		err      error
+	}{
+		{in: "05", from: iso8583.V1987, to: iso8583.V1987, out: "05"},
+		{in: "05", from: iso8583.V1987, to: iso8583.V1993, out: "100"},
+		{in: "51", from: iso8583.V1987, to: iso8583.V2003, out: "1160"},
+		{in: "209", from: iso8583.V1993, to: iso8583.V1987, out: "43"},
+		{in: "909", from: iso8583.V1993, to: iso8583.V1987, out: "06"},
+		{in: "9111", from: iso8583.V2003, to: iso8583.V1993, out: "911"},
+		{in: "0000", from: iso8583.V2003, to: iso8583.V1987, out: "00"},
+		{in: "Z3", from: iso8583.V1987, to: iso8583.V1993, err: errors.NotImplemented},
+		{in: "125", from: iso8583.V1993, to: iso8583.V1987, err: errors.NotImplemented},
+		{in: "05", from: iso8583.V1993, to: iso8583.V1987, err: errors.Data},
+		{in: "05", from: iso8583.V1987, to: 9, err: errors.Data},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := response.Convert(tt.in, tt.from, tt.to)
+			are := is.New(t)
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	var (
+		are = is.New(t)
+		m   = &iso8583.Message{MTI: iso8583.NewMTI(iso8583.V1987, iso8583.Authorization, iso8583.RequestResponse)}
+	)
+	// The action code is converted to the scheme of 1987.
+	are.NoErr(response.Set(m, "116"))
+	c, err := response.Get(m)
+	are.NoErr(err)
+	are.Equal(c.Value, "51")
+	are.Equal(c.Action, response.Decline)
+
+	// The spec of a version of 1993 defines an action code with three digits.
+	m = &iso8583.Message{
+		MTI:  iso8583.NewMTI(iso8583.V1993, iso8583.Authorization, iso8583.RequestResponse),
+		Spec: field.Spec{39: {Format: field.Numeric, Size: 3}},
+	}
+	are.NoErr(response.Set(m, "00"))
+	c, err = response.Get(m)
+	are.NoErr(err)
+	are.Equal(c.Value, "000")
+	are.Equal(c.Action, response.Approve)
+
+	are.True(response.Set(m, "1") != nil)
+	_, err = response.Get(&iso8583.Message{MTI: iso8583.NewMTI(0, 1, 1)})
+	are.True(err != nil)
+}
+
+func TestAction_MarshalText(t *testing.T) {
+	var (
+		are = is.New(t)
+		a   response.Action
+	)
+	b, err := json.Marshal(response.ISO1987.Lookup("04"))
+	are.NoErr(err)
+	are.Equal(string(b), `{"value":"04","action":"pick-up","description":"Pick-up card"}`)
+	are.NoErr(json.Unmarshal([]byte(`"retry"`), &a))
+	are.Equal(a, response.Retry)
+	are.True(json.Unmarshal([]byte(`"maybe"`), &a) != nil)
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package simulator
+
+import (
+	"encoding/hex"
+	"io"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/encoding"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/response"
+)
+
+// List of response codes used by default, in the scheme of ISO 8583:1987.
+// They are converted to the scheme of the version of the request, see response.Set.
+const (
+	// Approved is the response code of the requests matching no rule.
+	Approved = "00"
+	// Unavailable is the response code of the requests received before the sign-on.
+	Unavailable = "91"
+)
+
+// List of network management information codes (field 70).
+const (
+	SignOn  = "001"
+	SignOff = "002"
+	Echo    = "301"
+)
+
+// omitted lists the fields of the request never echoed in the response:
+// the bitmap, the card data, the PIN block, the chip data and the MAC.
+var omitted = map[field.ID]bool{
+	1: true, 14: true, 35: true, 36: true, 45: true, 52: true, 55: true, 64: true, 128: true,
+}
+
+// Scenario describes the behavior of the host.
+type Scenario struct {
+	// Format is the encoding of the messages: ascii, bcd or ebcdic.
+	Format string `yaml:"format"`
+	// Header is true if the messages start with their length.
+	Header bool `yaml:"header"`
+	// Spec overrides the definition of some data elements.
+	Spec field.Spec `yaml:"spec"`
+	// Framing is the length prefix of the frames: 2b, 4b, 4a or 2bcd.
+	Framing string `yaml:"framing"`
+	// Timeout closes the connections idle for this duration. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// SignOn requires a sign-on on the connection before approving other requests.
+	SignOn bool `yaml:"sign_on"`
+	// Rules are evaluated in order, the first matching one applies.
+	Rules []Rule `yaml:"rules"`
+	// Default applies to the requests matching no rule. By default, they are approved.
+	Default Action `yaml:"default"`
+
+	format encoding.Format
+	prefix frame.Prefix
+}
+
+// Rule is an action applied to the requests matching all its criteria.
+type Rule struct {
+	Name   string `yaml:"name"`
+	Match  Match  `yaml:"match"`
+	Action `yaml:",inline"`
+}
+
+// Match lists the criteria of a rule. An empty criterion matches any request.
+// The patterns use the syntax of path.Match, like 4* or 01?0.
+type Match struct {
+	// MTI lists the patterns of message type identifier.
+	MTI []string `yaml:"mti"`
+	// PAN lists the patterns of primary account number (field 2).
+	PAN []string `yaml:"pan"`
+	// ProcessingCode lists the patterns of processing code (field 3).
+	ProcessingCode []string `yaml:"processing_code"`
+	// MCC lists the patterns of merchant type (field 18).
+	MCC []string `yaml:"mcc"`
+	// Amount is the range of transaction amount (field 4).
+	Amount *Range `yaml:"amount"`
+}
+
+// Range is an inclusive range of amounts, in minor units. A zero maximum means no upper bound.
+type Range struct {
+	Min int64 `yaml:"min"`
+	Max int64 `yaml:"max"`
+}
+
+// Action defines the response to a request.
+type Action struct {
+	// Response is the response code (field 39). By default, the request is approved.
+	Response string `yaml:"response"`
+	// Fields sets or overrides data elements of the response.
+	Fields map[field.ID]string `yaml:"fields"`
+	// Latency delays the response.
+	Latency time.Duration `yaml:"latency"`
+	// Drop is the probability, between 0 and 1, to not respond.
+	Drop float64 `yaml:"drop"`
+	// Close closes the connection instead of responding.
+	Close bool `yaml:"close"`
+}
+
+// Load reads a scenario in YAML and checks it.
+func Load(r io.Reader) (*Scenario, error) {
+	s := new(Scenario)
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	if err := d.Decode(s); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Decide returns the action to apply to the request.
+// Without sign-on, when required, only the network management requests are answered by the rules.
+// The signed argument indicates if a sign-on has been approved on the connection.
+func (s *Scenario) Decide(req *iso8583.Message, signed bool) Action {
+	network := req.MTI != nil && req.MTI.Class == iso8583.NetworkManagement
+	if s.SignOn && !signed && !network {
+		return Action{Response: Unavailable}
+	}
+	for _, r := range s.Rules {
+		if r.Match.match(req) {
+			return r.Action
+		}
+	}
+	if network {
+		return Action{Response: Approved}
+	}
+	return s.Default
+}
+
+// Respond returns the response to the request with this action.
+// The fields of the request are echoed, except the sensitive ones, then the response code,
+// in the scheme of the version of the request, and the fields of the action are set.
+func (s *Scenario) Respond(req *iso8583.Message, a Action) (*iso8583.Message, error) {
+	if req.MTI == nil || !req.MTI.Valid() || req.MTI.Function%2 != 0 {
+		// Only the requests, advices, notifications and instructions get a response.
+		return nil, errors.MTI
+	}
+	res := s.message()
+	res.MTI = &iso8583.MTI{
+		Version:  req.MTI.Version,
+		Class:    req.MTI.Class,
+		Function: req.MTI.Function + 1,
+		Origin:   req.MTI.Origin,
+	}
+	for id, f := range req.Data {
+		if !omitted[id] {
+			res.Data[id] = f
+		}
+	}
+	code := a.Response
+	if code == "" {
+		code = Approved
+	}
+	if err := response.Set(res, code); err != nil {
+		return nil, err
+	}
+	for id, v := range a.Fields {
+		if err := s.set(res, id, v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// init parses and checks the options of the scenario.
+func (s *Scenario) init() (err error) {
+	s.format = encoding.ASCII
+	if s.Format != "" {
+		if s.format, err = encoding.Parse(s.Format); err != nil {
+			return err
+		}
+	}
+	s.prefix = frame.Binary2
+	if s.Framing != "" {
+		if s.prefix, err = frame.ParsePrefix(s.Framing); err != nil {
+			return err
+		}
+	}
+	if err = s.check(s.Default); err != nil {
+		return err
+	}
+	for _, r := range s.Rules {
+		for _, list := range [][]string{r.Match.MTI, r.Match.PAN, r.Match.ProcessingCode, r.Match.MCC} {
+			for _, p := range list {
+				if _, err = path.Match(p, ""); err != nil {
+					return err
+				}
+			}
+		}
+		if r.Match.Amount != nil && r.Match.Amount.Max > 0 && r.Match.Amount.Max < r.Match.Amount.Min {
+			return errors.OutOfRange
+		}
+		if err = s.check(r.Action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check verifies that the action builds valid data elements in at least one version of the standard.
+func (s *Scenario) check(a Action) (err error) {
+	if a.Drop < 0 || a.Drop > 1 {
+		return errors.OutOfRange
+	}
+	for v := iso8583.Version(iso8583.V1987); v.Valid(); v++ {
+		if err = s.checkIn(v, a); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// checkIn verifies that the action builds valid data elements in a response of this version.
+func (s *Scenario) checkIn(version iso8583.Version, a Action) error {
+	m := s.message()
+	m.MTI = iso8583.NewMTI(uint8(version), iso8583.Financial, iso8583.RequestResponse)
+	if a.Response != "" {
+		if err := response.Set(m, a.Response); err != nil {
+			return err
+		}
+	}
+	for id, v := range a.Fields {
+		if err := s.set(m, id, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// message returns an empty message with the format of the scenario.
+func (s *Scenario) message() *iso8583.Message {
+	return &iso8583.Message{
+		Format: s.format,
+		Header: s.Header,
+		Spec:   s.Spec,
+		Data:   iso8583.Fields{},
+	}
+}
+
+// set sets the value of the data element, in hexadecimal for the binary ones, and checks it.
+func (s *Scenario) set(m *iso8583.Message, id field.ID, v string) error {
+	if id <= 1 {
+		return errors.New(errors.Data, int(id))
+	}
+	f := &field.Data{Element: m.Element(id), Pos: id}
+	if f.Format == field.Binary {
+		b, err := hex.DecodeString(v)
+		if err != nil {
+			return errors.New(errors.Data, int(id))
+		}
+		if err = f.SetBytes(b); err != nil {
+			return errors.New(err, int(id))
+		}
+	} else {
+		f.Value = []byte(v)
+		if f.Type != field.Fixed {
+			f.Size = len(f.Value)
+		}
+	}
+	if _, err := field.Marshal(f); err != nil {
+		return errors.New(err, int(id))
+	}
+	m.Data[id] = f
+	return nil
+}
+
+// match returns true if the request matches all the criteria.
+func (m Match) match(req *iso8583.Message) bool {
+	if !matchAny(m.MTI, req.Type()) {
+		return false
+	}
+	if !matchAny(m.PAN, value(req, 2)) {
+		return false
+	}
+	if !matchAny(m.ProcessingCode, value(req, 3)) {
+		return false
+	}
+	if !matchAny(m.MCC, value(req, 18)) {
+		return false
+	}
+	if m.Amount == nil {
+		return true
+	}
+	f, ok := req.Data[4]
+	if !ok {
+		return false
+	}
+	n, err := f.Int64()
+	if err != nil {
+		return false
+	}
+	return n >= m.Amount.Min && (m.Amount.Max == 0 || n <= m.Amount.Max)
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func value(m *iso8583.Message, id field.ID) string {
+	f, ok := m.Data[id]
+	if !ok {
+		return ""
+	}
+	return f.String()
+}
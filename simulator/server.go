@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package simulator
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/response"
+)
+
+// Server answers the requests received on its connections with a scenario.
+type Server struct {
+	Scenario *Scenario
+	// ErrorLog logs the errors on the connections. By default, they are discarded.
+	ErrorLog *log.Logger
+
+	mu     sync.Mutex
+	ln     []net.Listener
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// Serve accepts the connections on the listener and serves them until the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		_ = l.Close()
+		return net.ErrClosed
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// Close closes the listeners and the connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var err error
+	for _, l := range s.ln {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	return err
+}
+
+// serve reads the requests of the connection and responds to each of them on its own,
+// so a slow response does not delay the next ones.
+func (s *Server) serve(conn net.Conn) {
+	if !s.add(conn) {
+		_ = conn.Close()
+		return
+	}
+	defer s.remove(conn)
+
+	var (
+		c  = &session{Conn: conn}
+		wg sync.WaitGroup
+	)
+	for {
+		if s.Scenario.Timeout > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(s.Scenario.Timeout)); err != nil {
+				break
+			}
+		}
+		b, err := s.Scenario.prefix.Read(conn)
+		if err != nil {
+			break
+		}
+		req := s.Scenario.message()
+		if err = iso8583.Unmarshal(b, req); err != nil {
+			s.logf("%s: %s", conn.RemoteAddr(), err)
+			continue
+		}
+		a := s.Scenario.Decide(req, c.signed())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.respond(c, req, a)
+		}()
+	}
+	wg.Wait()
+}
+
+// respond applies the action to the request.
+func (s *Server) respond(c *session, req *iso8583.Message, a Action) {
+	if a.Latency > 0 {
+		time.Sleep(a.Latency)
+	}
+	if a.Close {
+		_ = c.Close()
+		return
+	}
+	if a.Drop > 0 && rand.Float64() < a.Drop {
+		return
+	}
+	res, err := s.Scenario.Respond(req, a)
+	if err != nil {
+		s.logf("%s: %s", c.RemoteAddr(), err)
+		return
+	}
+	b, err := iso8583.Marshal(res)
+	if err != nil {
+		s.logf("%s: %s", c.RemoteAddr(), err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err = s.Scenario.prefix.Write(c, b); err != nil {
+		s.logf("%s: %s", c.RemoteAddr(), err)
+		return
+	}
+	c.update(req, res)
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+	}
+}
+
+func (s *Server) track(l net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.ln = append(s.ln, l)
+	return true
+}
+
+func (s *Server) add(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]bool)
+	}
+	s.conns[c] = true
+	return true
+}
+
+func (s *Server) remove(c net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+	_ = c.Close()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// session is a connection with its sign-on state.
+type session struct {
+	net.Conn
+	// mu serializes the writes of the responses and protects the sign-on state.
+	mu sync.Mutex
+	on bool
+}
+
+// signed returns true if the connection is signed on.
+func (c *session) signed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.on
+}
+
+// update updates the sign-on state with the response sent to the network management request:
+// only an approved sign-on or sign-off changes it. The caller must hold mu.
+func (c *session) update(req, res *iso8583.Message) {
+	if req.MTI == nil || req.MTI.Class != iso8583.NetworkManagement {
+		return
+	}
+	if c, err := response.Get(res); err != nil || c.Action != response.Approve {
+		return
+	}
+	switch value(req, 70) {
+	case SignOn:
+		c.on = true
+	case SignOff:
+		c.on = false
+	}
+}
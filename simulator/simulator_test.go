@@ -0,0 +1,277 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package simulator_test
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/frame"
+	"github.com/rvflash/iso8583/simulator"
+)
+
+const scenario = `
+framing: 2b
+sign_on: true
+rules:
+  - name: insufficient funds
+    match:
+      mti: ["01?0", "02?0"]
+      amount: {min: 100000}
+    response: "51"
+  - name: restricted card
+    match:
+      pan: ["4000*"]
+    response: "62"
+  - name: gambling
+    match:
+      mcc: ["7995"]
+      processing_code: ["00*"]
+    response: "57"
+  - name: slow issuer
+    match:
+      pan: ["5*"]
+    latency: 200ms
+    drop: 1
+default:
+  fields:
+    38: "A1B2C3"
+`
+
+func newMessage(mti string, values map[field.ID]string) *iso8583.Message {
+	m := &iso8583.Message{Data: iso8583.Fields{}}
+	m.MTI, _ = iso8583.ParseMTI(mti)
+	for id, v := range values {
+		f := field.New(id)
+		f.Value = []byte(v)
+		if f.Type != field.Fixed {
+			f.Size = len(v)
+		}
+		m.Data[id] = f
+	}
+	return m
+}
+
+func load(t *testing.T) *simulator.Scenario {
+	s, err := simulator.Load(strings.NewReader(scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoad(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		err bool
+	}{
+		{in: ""},
+		{in: scenario},
+		{in: "format: utf8", err: true},
+		{in: "framing: 3b", err: true},
+		{in: "unknown: true", err: true},
+		{in: "rules: [{match: {pan: ['[']}}]", err: true},
+		{in: "rules: [{match: {amount: {min: 10, max: 5}}}]", err: true},
+		{in: "rules: [{response: ABC}]", err: true},
+		{in: "rules: [{response: '116'}]"},
+		{in: "default: {drop: 2}", err: true},
+		{in: "default: {fields: {38: '1234567'}}", err: true},
+		{in: "spec: {48: {type: lllvar, format: ans, size: 999}}\ndefault: {fields: {48: 'abc'}}"},
+		{in: "spec: {48: {type: lllvar, format: xyz, size: 999}}", err: true},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			_, err := simulator.Load(strings.NewReader(tt.in))
+			is.New(t).Equal(err != nil, tt.err)
+		})
+	}
+}
+
+func TestScenario_Decide(t *testing.T) {
+	s := load(t)
+	for i, tt := range []struct {
+		in     *iso8583.Message
+		signed bool
+		code   string
+		drop   bool
+	}{
+		{in: newMessage("0800", map[field.ID]string{70: "301"}), code: "00"},
+		{in: newMessage("0100", map[field.ID]string{2: "4111111111111111", 4: "000000001000"}), code: "91"},
+		{in: newMessage("0100", map[field.ID]string{2: "4111111111111111", 4: "000000001000"}), signed: true},
+		{in: newMessage("0200", map[field.ID]string{2: "4111111111111111", 4: "000000100000"}), signed: true, code: "51"},
+		{in: newMessage("0400", map[field.ID]string{2: "4111111111111111", 4: "000000100000"}), signed: true},
+		{in: newMessage("0100", map[field.ID]string{2: "4000001234567899", 4: "000000001000"}), signed: true, code: "62"},
+		{in: newMessage("0100", map[field.ID]string{3: "000000", 18: "7995"}), signed: true, code: "57"},
+		{in: newMessage("0100", map[field.ID]string{3: "200000", 18: "7995"}), signed: true},
+		{in: newMessage("0100", map[field.ID]string{2: "5100001234567890"}), signed: true, drop: true},
+	} {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			a := s.Decide(tt.in, tt.signed)
+			are := is.New(t)
+			are.Equal(a.Response, tt.code)
+			are.Equal(a.Drop == 1, tt.drop)
+		})
+	}
+}
+
+func TestScenario_Respond(t *testing.T) {
+	var (
+		are = is.New(t)
+		s   = load(t)
+		req = newMessage("0200", map[field.ID]string{
+			2: "4111111111111111", 3: "000000", 4: "000000001000", 11: "000042", 35: "4111111111111111=2512",
+		})
+	)
+	res, err := s.Respond(req, s.Decide(req, true))
+	are.NoErr(err)
+	are.Equal(res.Type(), "0210")
+	are.Equal(res.Data[11].String(), "000042")
+	are.Equal(res.Data[39].String(), "00")
+	are.Equal(res.Data[38].String(), "A1B2C3")
+	_, ok := res.Data[35]
+	are.True(!ok)
+
+	_, err = s.Respond(res, simulator.Action{})
+	are.True(err != nil)
+
+	// The response codes are converted to the action codes of ISO 8583:1993.
+	req.MTI, err = iso8583.ParseMTI("1200")
+	are.NoErr(err)
+	res, err = s.Respond(req, s.Decide(req, true))
+	are.NoErr(err)
+	are.Equal(res.Type(), "1210")
+	are.Equal(res.Data[39].String(), "000")
+	req.Data[2].(*field.Data).Value = []byte("4000001234567899")
+	res, err = s.Respond(req, s.Decide(req, true))
+	are.NoErr(err)
+	are.Equal(res.Data[39].String(), "104")
+}
+
+// exchange sends the request on the connection and returns its response.
+func exchange(conn net.Conn, req *iso8583.Message) (*iso8583.Message, error) {
+	b, err := iso8583.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = frame.Binary2.Write(conn, b); err != nil {
+		return nil, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if b, err = frame.Binary2.Read(conn); err != nil {
+		return nil, err
+	}
+	res := new(iso8583.Message)
+	return res, iso8583.Unmarshal(b, res)
+}
+
+func TestServer_Serve(t *testing.T) {
+	var (
+		are = is.New(t)
+		srv = &simulator.Server{Scenario: load(t)}
+	)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	done := make(chan error)
+	go func() { done <- srv.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	are.NoErr(err)
+	defer func() { _ = conn.Close() }()
+
+	auth := newMessage("0100", map[field.ID]string{2: "4111111111111111", 4: "000000001000", 11: "000001"})
+
+	res, err := exchange(conn, auth)
+	are.NoErr(err)
+	are.Equal(res.Data[39].String(), simulator.Unavailable)
+
+	res, err = exchange(conn, newMessage("0800", map[field.ID]string{11: "000002", 70: simulator.SignOn}))
+	are.NoErr(err)
+	are.Equal(res.Type(), "0810")
+	are.Equal(res.Data[39].String(), simulator.Approved)
+
+	res, err = exchange(conn, auth)
+	are.NoErr(err)
+	are.Equal(res.Type(), "0110")
+	are.Equal(res.Data[39].String(), simulator.Approved)
+
+	// The response is dropped: the client times out.
+	_, err = exchange(conn, newMessage("0100", map[field.ID]string{2: "5100001234567890", 11: "000003"}))
+	are.True(err != nil)
+
+	are.NoErr(srv.Close())
+	are.Equal(<-done, net.ErrClosed)
+}
+
+func TestServer_Serve_iso1993(t *testing.T) {
+	var (
+		are = is.New(t)
+		srv = &simulator.Server{Scenario: load(t)}
+	)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	done := make(chan error)
+	go func() { done <- srv.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	are.NoErr(err)
+	defer func() { _ = conn.Close() }()
+
+	res, err := exchange(conn, newMessage("1804", map[field.ID]string{11: "000001", 70: simulator.SignOn}))
+	are.NoErr(err)
+	are.Equal(res.Type(), "1814")
+	are.Equal(res.Data[39].String(), "000")
+
+	// The connection is signed on.
+	res, err = exchange(conn, newMessage("1100", map[field.ID]string{2: "4111111111111111", 4: "000000001000", 11: "000002"}))
+	are.NoErr(err)
+	are.Equal(res.Type(), "1110")
+	are.Equal(res.Data[39].String(), "000")
+	are.Equal(res.Data[38].String(), "A1B2C3")
+
+	are.NoErr(srv.Close())
+	are.Equal(<-done, net.ErrClosed)
+}
+
+func TestServer_Serve_declinedSignOn(t *testing.T) {
+	are := is.New(t)
+	s, err := simulator.Load(strings.NewReader(`
+framing: 2b
+sign_on: true
+rules:
+  - name: sign-on refused
+    match:
+      mti: ["0800"]
+    response: "05"
+`))
+	are.NoErr(err)
+	srv := &simulator.Server{Scenario: s}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	are.NoErr(err)
+	done := make(chan error)
+	go func() { done <- srv.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	are.NoErr(err)
+	defer func() { _ = conn.Close() }()
+
+	res, err := exchange(conn, newMessage("0800", map[field.ID]string{11: "000001", 70: simulator.SignOn}))
+	are.NoErr(err)
+	are.Equal(res.Data[39].String(), "05")
+
+	// The connection is not signed on.
+	res, err = exchange(conn, newMessage("0100", map[field.ID]string{2: "4111111111111111", 4: "000000001000", 11: "000002"}))
+	are.NoErr(err)
+	are.Equal(res.Data[39].String(), simulator.Unavailable)
+
+	are.NoErr(srv.Close())
+	are.Equal(<-done, net.ErrClosed)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package tlv_test
+
+import (
+	"testing"
+
+	"github.com/rvflash/iso8583/tlv"
+)
+
+func FuzzDecode(f *testing.F) {
+	f.Add(unhex("9F02060000000025009F1A020250"))
+	f.Add(unhex("7081039F0201FF"))
+	f.Add(unhex("00FF5F2A0209788200"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		list, err := tlv.Decode(data)
+		if err != nil {
+			return
+		}
+		b, err := tlv.Encode(list)
+		if err != nil {
+			t.Fatalf("%X: %s", data, err)
+		}
+		if _, err = tlv.Decode(b); err != nil {
+			t.Fatalf("%X: %s", b, err)
+		}
+	})
+}
+
+func FuzzText_Decode(f *testing.F) {
+	f.Add("01031230205ABCDE")
+	f.Add("01-1")
+	f.Fuzz(func(t *testing.T, s string) {
+		list, err := tlv.Subfields.Decode(s)
+		if err != nil {
+			return
+		}
+		if _, err = tlv.Subfields.Encode(list); err != nil {
+			t.Fatalf("%q: %s", s, err)
+		}
+	})
+}
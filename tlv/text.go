@@ -0,0 +1,58 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package tlv
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// Text is a tag-length-value encoding with a fixed number of characters for the tag
+// and the decimal length, as used by the sub-elements of the private fields.
+type Text struct {
+	TagLen, LenLen int
+}
+
+// Subfields is the common encoding of sub-elements: two characters for the tag and two digits for the length.
+var Subfields = Text{TagLen: 2, LenLen: 2}
+
+// Decode parses the data objects.
+func (e Text) Decode(s string) ([]TLV, error) {
+	var list []TLV
+	for len(s) > 0 {
+		if len(s) < e.TagLen+e.LenLen {
+			return nil, errors.OutOfRange
+		}
+		n, err := strconv.Atoi(s[e.TagLen : e.TagLen+e.LenLen])
+		if err != nil || n < 0 {
+			return nil, errors.Length
+		}
+		a := e.TagLen + e.LenLen
+		if len(s) < a+n {
+			return nil, errors.OutOfRange
+		}
+		list = append(list, TLV{Tag: s[:e.TagLen], Value: []byte(s[a : a+n])})
+		s = s[a+n:]
+	}
+	return list, nil
+}
+
+// Encode returns the encoding of the data objects.
+func (e Text) Encode(list []TLV) (string, error) {
+	var out string
+	for _, t := range list {
+		if len(t.Tag) != e.TagLen {
+			return "", errors.Data
+		}
+		l := strconv.Itoa(len(t.Value))
+		if len(l) > e.LenLen {
+			return "", errors.Length
+		}
+		out += t.Tag + strings.Repeat("0", e.LenLen-len(l)) + l + string(t.Value)
+	}
+	return out, nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package tlv implements the encoding and decoding of tag-length-value data,
+// like the BER-TLV of the EMV data (field 55) or the sub-elements of private fields.
+package tlv
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// TLV is a data object.
+type TLV struct {
+	// Tag is the tag in hexadecimal with BER-TLV, as is with the Text encoding.
+	Tag   string
+	Value []byte
+	// Children are the data objects of a constructed BER-TLV.
+	Children []TLV
+}
+
+// Constructed returns true if the BER-TLV data object contains other data objects.
+func (t TLV) Constructed() bool {
+	b, err := hex.DecodeString(t.Tag)
+	return err == nil && len(b) > 0 && b[0]&0x20 != 0
+}
+
+// maxDepth is the maximum number of nested data objects.
+const maxDepth = 32
+
+// Decode parses the BER-TLV data objects, as defined in ISO 7816-4 and EMV 4.3 Book 3.
+func Decode(b []byte) ([]TLV, error) {
+	return decode(b, 0)
+}
+
+func decode(b []byte, depth int) ([]TLV, error) {
+	if depth > maxDepth {
+		return nil, errors.Data
+	}
+	var list []TLV
+	for len(b) > 0 {
+		// Padding between the data objects.
+		if b[0] == 0x00 || b[0] == 0xFF {
+			b = b[1:]
+			continue
+		}
+		n := 1
+		if b[0]&0x1F == 0x1F {
+			for ; n < len(b) && b[n]&0x80 != 0; n++ {
+			}
+			n++
+		}
+		if n > len(b) {
+			return nil, errors.OutOfRange
+		}
+		t := TLV{Tag: strings.ToUpper(hex.EncodeToString(b[:n]))}
+		b = b[n:]
+		size, n, err := length(b)
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+		if size > len(b) {
+			return nil, errors.OutOfRange
+		}
+		t.Value = b[:size]
+		b = b[size:]
+		if t.Constructed() {
+			if t.Children, err = decode(t.Value, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+// Encode returns the BER-TLV encoding of the data objects.
+// The value of a constructed data object is built with its children, if any.
+func Encode(list []TLV) ([]byte, error) {
+	var out []byte
+	for _, t := range list {
+		tag, err := hex.DecodeString(t.Tag)
+		if err != nil || len(tag) == 0 {
+			return nil, errors.Data
+		}
+		v := t.Value
+		if len(t.Children) > 0 {
+			if v, err = Encode(t.Children); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, tag...)
+		switch n := len(v); {
+		case n < 0x80:
+			out = append(out, byte(n))
+		case n <= 0xFF:
+			out = append(out, 0x81, byte(n))
+		case n <= 0xFFFF:
+			out = append(out, 0x82, byte(n>>8), byte(n))
+		default:
+			return nil, errors.Length
+		}
+		out = append(out, v...)
+	}
+	return out, nil
+}
+
+// Flatten returns the values of the primitive data objects by path.
+// The path joins the tags of the parents and of the data object with a dot.
+func Flatten(list []TLV) map[string][]byte {
+	out := make(map[string][]byte)
+	flatten(out, "", list)
+	return out
+}
+
+func flatten(out map[string][]byte, path string, list []TLV) {
+	for _, t := range list {
+		p := t.Tag
+		if path != "" {
+			p = path + "." + t.Tag
+		}
+		if len(t.Children) > 0 {
+			flatten(out, p, t.Children)
+			continue
+		}
+		out[p] = t.Value
+	}
+}
+
+// length returns the length of the value and the number of bytes used to encode it.
+func length(b []byte) (size, n int, err error) {
+	if len(b) == 0 {
+		return 0, 0, errors.OutOfRange
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1, nil
+	}
+	n = int(b[0] & 0x7F)
+	if n == 0 || n > 3 {
+		return 0, 0, errors.Length
+	}
+	if len(b) < n+1 {
+		return 0, 0, errors.OutOfRange
+	}
+	for _, v := range b[1 : n+1] {
+		size = size<<8 | int(v)
+	}
+	return size, n + 1, nil
+}
+
+// List of the EMV tags with sensitive values.
+var sensitive = map[string]bool{
+	"57":   true, // Track 2 equivalent data
+	"5A":   true, // Application PAN
+	"5F20": true, // Cardholder name
+	"9F1F": true, // Track 1 discretionary data
+	"9F20": true, // Track 2 discretionary data
+	"9F6B": true, // Track 2 data
+}
+
+// Sensitive returns true if the value of the EMV tag must not be disclosed.
+func Sensitive(tag string) bool {
+	return sensitive[strings.ToUpper(tag)]
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package tlv_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/tlv"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			in   string
+			flat map[string]string
+			err  error
+		}{
+			{
+				in:   "9F02060000000010009F360200FF5F2A020978",
+				flat: map[string]string{"9F02": "000000001000", "9F36": "00FF", "5F2A": "0978"},
+			},
+			{
+				in:   "7006" + "5A0412345678" + "00" + "9A03191231",
+				flat: map[string]string{"70.5A": "12345678", "9A": "191231"},
+			},
+			{in: "9F0281" + "03" + "010203", flat: map[string]string{"9F02": "010203"}},
+			{in: "9F02", err: errors.OutOfRange},
+			{in: "9F020600", err: errors.OutOfRange},
+			{in: "9F028400000001", err: errors.Length},
+			{in: "9F", err: errors.OutOfRange},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tlv.Decode(unhex(tt.in))
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			flat := tlv.Flatten(out)
+			are.Equal(len(flat), len(tt.flat))
+			for k, v := range tt.flat {
+				are.Equal(strings.ToUpper(hex.EncodeToString(flat[k])), v)
+			}
+			b, err := tlv.Encode(out)
+			are.NoErr(err)
+			// Encodes without padding and with the shortest length.
+			res, err := tlv.Decode(b)
+			are.NoErr(err)
+			are.Equal(tlv.Flatten(res), flat)
+		})
+	}
+	// Too many nested data objects.
+	deep := tlv.TLV{Tag: "70"}
+	for k := 0; k < 40; k++ {
+		deep = tlv.TLV{Tag: "70", Children: []tlv.TLV{deep}}
+	}
+	b, err := tlv.Encode([]tlv.TLV{deep})
+	are.NoErr(err)
+	_, err = tlv.Decode(b)
+	are.Equal(err, errors.Data)
+}
+
+func TestText_Decode(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			in  string
+			out []tlv.TLV
+			err error
+		}{
+			{in: "0103abc4202XY", out: []tlv.TLV{{Tag: "01", Value: []byte("abc")}, {Tag: "42", Value: []byte("XY")}}},
+			{in: "0105abc", err: errors.OutOfRange},
+			{in: "01AAabc", err: errors.Length},
+			{in: "010", err: errors.OutOfRange},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tlv.Subfields.Decode(tt.in)
+			are.Equal(err, tt.err)
+			are.Equal(out, tt.out)
+			if tt.err == nil {
+				s, err := tlv.Subfields.Encode(out)
+				are.NoErr(err)
+				are.Equal(s, tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package tr31
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+)
+
+// HeaderLen is the length of the header without its optional blocks.
+const HeaderLen = 16
+
+// List of key block versions.
+const (
+	// VersionA uses the key variant binding method with Triple DES (deprecated).
+	VersionA = 'A'
+	// VersionB uses the key derivation binding method with Triple DES.
+	VersionB = 'B'
+	// VersionC uses the key variant binding method with Triple DES.
+	VersionC = 'C'
+	// VersionD uses the key derivation binding method with AES.
+	VersionD = 'D'
+)
+
+// List of common key usages.
+const (
+	UsageBDK           = "B0"
+	UsageData          = "D0"
+	UsageKeyEncryption = "K0"
+	UsageKeyBlock      = "K1"
+	UsageMACAlg1       = "M1"
+	UsageMACAlg3       = "M3"
+	UsageCMAC          = "M6"
+	UsagePIN           = "P0"
+)
+
+// List of key algorithms.
+const (
+	AlgorithmAES  = 'A'
+	AlgorithmDES  = 'D'
+	AlgorithmHMAC = 'H'
+	AlgorithmTDES = 'T'
+)
+
+// List of modes of use.
+const (
+	ModeBoth        = 'B'
+	ModeMAC         = 'C'
+	ModeDecrypt     = 'D'
+	ModeEncrypt     = 'E'
+	ModeGenerate    = 'G'
+	ModeNone        = 'N'
+	ModeSignature   = 'S'
+	ModeVerify      = 'V'
+	ModeDerivation  = 'X'
+	ModeVariantOnly = 'Y'
+)
+
+// List of exportability values.
+const (
+	Exportable    = 'E'
+	NonExportable = 'N'
+	Sensitive     = 'S'
+)
+
+// paddingBlock is the identifier of the optional block used to align the header on the cipher block size.
+const paddingBlock = "PB"
+
+// Block is an optional block of the header.
+type Block struct {
+	ID   string
+	Data string
+}
+
+// Header is the clear part of a key block describing the key.
+type Header struct {
+	Version       byte
+	Usage         string
+	Algorithm     byte
+	ModeOfUse     byte
+	KeyVersion    string
+	Exportability byte
+	Blocks        []Block
+}
+
+// ParseHeader parses the header of the key block.
+// It returns the header, its length and the length of the key block.
+func ParseHeader(s string) (h *Header, size, length int, err error) {
+	if len(s) < HeaderLen {
+		return nil, 0, 0, errors.Length
+	}
+	length, err = strconv.Atoi(s[1:5])
+	if err != nil || length < HeaderLen {
+		return nil, 0, 0, errors.Data
+	}
+	n, err := strconv.Atoi(s[12:14])
+	if err != nil {
+		return nil, 0, 0, errors.Data
+	}
+	h = &Header{
+		Version:       s[0],
+		Usage:         s[5:7],
+		Algorithm:     s[7],
+		ModeOfUse:     s[8],
+		KeyVersion:    s[9:11],
+		Exportability: s[11],
+	}
+	size = HeaderLen
+	for i := 0; i < n; i++ {
+		if len(s) < size+4 {
+			return nil, 0, 0, errors.Length
+		}
+		l, err := strconv.ParseUint(s[size+2:size+4], 16, 8)
+		if err != nil || l < 4 || len(s) < size+int(l) {
+			return nil, 0, 0, errors.Data
+		}
+		h.Blocks = append(h.Blocks, Block{ID: s[size : size+2], Data: s[size+4 : size+int(l)]})
+		size += int(l)
+	}
+	return h, size, length, nil
+}
+
+// Block returns the data of the optional block with this identifier.
+func (h *Header) Block(id string) (string, bool) {
+	for _, b := range h.Blocks {
+		if b.ID == id {
+			return b.Data, true
+		}
+	}
+	return "", false
+}
+
+// encode returns the header for a key block of this length, with its optional blocks,
+// padded to be a multiple of the block size.
+func (h *Header) encode(length, blockSize int) (string, error) {
+	var (
+		blocks strings.Builder
+		n      int
+	)
+	for _, b := range h.Blocks {
+		if b.ID == paddingBlock {
+			continue
+		}
+		if len(b.ID) != 2 || len(b.Data)+4 > 0xFF {
+			return "", errors.Data
+		}
+		blocks.WriteString(fmt.Sprintf("%s%02X%s", b.ID, len(b.Data)+4, b.Data))
+		n++
+	}
+	if r := (HeaderLen + blocks.Len()) % blockSize; r != 0 {
+		pad := blockSize - r
+		if pad < 4 {
+			pad += blockSize
+		}
+		blocks.WriteString(fmt.Sprintf("%s%02X%s", paddingBlock, pad, strings.Repeat("0", pad-4)))
+		n++
+	}
+	if len(h.Usage) != 2 || len(h.KeyVersion) != 2 || n > 99 {
+		return "", errors.Data
+	}
+	length += HeaderLen + blocks.Len()
+	if length > 9999 {
+		return "", errors.Length
+	}
+	return fmt.Sprintf(
+		"%c%04d%s%c%c%s%c%02d00%s",
+		h.Version, length, h.Usage, h.Algorithm, h.ModeOfUse, h.KeyVersion, h.Exportability, n, blocks.String(),
+	), nil
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+// Package tr31 parses, wraps and unwraps TR-31 (ANSI X9.143) key blocks.
+// Only the key derivation binding methods (versions B and D) are supported to wrap or unwrap keys.
+package tr31
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/internal/des3"
+	"github.com/rvflash/iso8583/mac"
+)
+
+// List of key usages used to derive the key block keys.
+const (
+	encryption     uint16 = 0x0000
+	authentication uint16 = 0x0001
+)
+
+// Spec redefines the message security code (field 96) as variable binary data, to carry a key block
+// of up to 499 characters: its ISO 8583 definition, b 64, is too short.
+var Spec = field.Spec{96: {Type: field.LLLVar, Format: field.Binary, Size: 3992}}
+
+// Wrap returns the key block protecting the key with the key block protection key (KBPK).
+// The version of the header defines the binding method.
+func Wrap(kbpk []byte, h Header, key []byte) (string, error) {
+	v, err := version(h.Version)
+	if err != nil {
+		return "", err
+	}
+	kbek, kbak, err := v.keys(kbpk)
+	if err != nil {
+		return "", err
+	}
+	// Key length in bits, the key, then a random padding up to the block size.
+	n := 2 + len(key)
+	if r := n % v.blockSize; r != 0 {
+		n += v.blockSize - r
+	}
+	clear := make([]byte, n)
+	binary.BigEndian.PutUint16(clear, uint16(len(key)*8))
+	copy(clear[2:], key)
+	if _, err = rand.Read(clear[2+len(key):]); err != nil {
+		return "", err
+	}
+	header, err := h.encode(2*(len(clear)+v.macSize), v.blockSize)
+	if err != nil {
+		return "", err
+	}
+	sum, err := v.mac(kbak, header, clear)
+	if err != nil {
+		return "", err
+	}
+	b, err := v.newCipher(kbek)
+	if err != nil {
+		return "", errors.Key
+	}
+	cipher.NewCBCEncrypter(b, sum[:v.blockSize]).CryptBlocks(clear, clear)
+
+	return header + strings.ToUpper(hex.EncodeToString(clear)+hex.EncodeToString(sum)), nil
+}
+
+// Unwrap verifies the key block with the key block protection key (KBPK) and returns its header and the key.
+func Unwrap(kbpk []byte, s string) (*Header, []byte, error) {
+	h, size, length, err := ParseHeader(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length != len(s) {
+		return nil, nil, errors.Length
+	}
+	v, err := version(h.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	kbek, kbak, err := v.keys(kbpk)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := hex.DecodeString(s[size:])
+	if err != nil {
+		return nil, nil, errors.Data
+	}
+	n := len(data) - v.macSize
+	if n < v.blockSize || n%v.blockSize != 0 {
+		return nil, nil, errors.Length
+	}
+	b, err := v.newCipher(kbek)
+	if err != nil {
+		return nil, nil, errors.Key
+	}
+	clear := make([]byte, n)
+	cipher.NewCBCDecrypter(b, data[n:n+v.blockSize]).CryptBlocks(clear, data[:n])
+	sum, err := v.mac(kbak, s[:size], clear)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hmac.Equal(sum, data[n:]) {
+		return nil, nil, errors.MAC
+	}
+	l := int(binary.BigEndian.Uint16(clear)) / 8
+	if l == 0 || 2+l > n {
+		return nil, nil, errors.Length
+	}
+	return h, clear[2 : 2+l], nil
+}
+
+// FromField returns the key block carried by the field.
+// It is written as is in a text field and as bytes in a binary field, such as the field 96.
+func FromField(f field.Field) (string, error) {
+	d, ok := f.(*field.Data)
+	if !ok || d.Format != field.Binary {
+		return strings.TrimRight(f.String(), " "), nil
+	}
+	b, err := d.Bytes()
+	if err != nil {
+		return "", errors.New(errors.Data, int(f.ID()))
+	}
+	return string(bytes.TrimRight(b, "\x00 ")), nil
+}
+
+// NewField returns the field with this identifier carrying the key block, as defined by the specification.
+// The field 96 requires Spec.
+func NewField(spec field.Spec, id field.ID, s string) (*field.Data, error) {
+	var (
+		f   = spec.New(id)
+		err error
+	)
+	if f.Format == field.Binary {
+		err = f.SetBytes([]byte(s))
+	} else {
+		f.Value = []byte(s)
+		if f.Type != field.Fixed {
+			f.Size = len(f.Value)
+		}
+		if !f.Valid() {
+			err = errors.Data
+		}
+	}
+	if err != nil {
+		return nil, errors.New(err, int(id))
+	}
+	return f, nil
+}
+
+// binding describes a key derivation binding method.
+type binding struct {
+	blockSize int
+	macSize   int
+	newCipher func(key []byte) (cipher.Block, error)
+}
+
+func version(v byte) (*binding, error) {
+	switch v {
+	case VersionB:
+		return &binding{blockSize: des.BlockSize, macSize: 8, newCipher: des3.NewCipher}, nil
+	case VersionD:
+		return &binding{blockSize: aes.BlockSize, macSize: 16, newCipher: aes.NewCipher}, nil
+	case VersionA, VersionC:
+		return nil, errors.NotImplemented
+	default:
+		return nil, errors.Data
+	}
+}
+
+// keys derives the key block encryption key (KBEK) and the key block authentication key (KBAK)
+// from the key block protection key.
+func (v *binding) keys(kbpk []byte) (kbek, kbak []byte, err error) {
+	var alg uint16
+	switch {
+	case v.blockSize == des.BlockSize && len(kbpk) == 16:
+		alg = 0x0000
+	case v.blockSize == des.BlockSize && len(kbpk) == 24:
+		alg = 0x0001
+	case v.blockSize == aes.BlockSize && len(kbpk) == 16:
+		alg = 0x0002
+	case v.blockSize == aes.BlockSize && len(kbpk) == 24:
+		alg = 0x0003
+	case v.blockSize == aes.BlockSize && len(kbpk) == 32:
+		alg = 0x0004
+	default:
+		return nil, nil, errors.Key
+	}
+	if kbek, err = v.derive(kbpk, encryption, alg); err != nil {
+		return nil, nil, err
+	}
+	if kbak, err = v.derive(kbpk, authentication, alg); err != nil {
+		return nil, nil, err
+	}
+	return kbek, kbak, nil
+}
+
+// derive returns a key of the size of the KBPK, using CMAC as pseudo-random function.
+func (v *binding) derive(kbpk []byte, usage, alg uint16) ([]byte, error) {
+	var (
+		prf  = mac.CMAC{NewCipher: v.newCipher}
+		data = make([]byte, 8)
+		out  []byte
+	)
+	binary.BigEndian.PutUint16(data[1:], usage)
+	binary.BigEndian.PutUint16(data[4:], alg)
+	binary.BigEndian.PutUint16(data[6:], uint16(len(kbpk)*8))
+	for i := byte(1); len(out) < len(kbpk); i++ {
+		data[0] = i
+		b, err := prf.Sum(kbpk, data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	return out[:len(kbpk)], nil
+}
+
+// mac returns the MAC computed over the header and the clear key data.
+func (v *binding) mac(kbak []byte, header string, clear []byte) ([]byte, error) {
+	sum, err := mac.CMAC{NewCipher: v.newCipher}.Sum(kbak, append([]byte(header), clear...))
+	if err != nil {
+		return nil, err
+	}
+	return sum[:v.macSize], nil
+}
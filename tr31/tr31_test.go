@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Hervé Gouchet. All rights reserved.
+// Use of this source code is governed by the MIT License
+// that can be found in the LICENSE file.
+
+package tr31_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/rvflash/iso8583"
+	"github.com/rvflash/iso8583/errors"
+	"github.com/rvflash/iso8583/field"
+	"github.com/rvflash/iso8583/tr31"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+const (
+	tdesKBPK = "89E88CF7931444F334BD7547FC3F380C"
+	tdesKey  = "F039121BEC83D26B169BDCD5B22AAF8F"
+	// Test vector published with ANSI X9.143.
+	aesKBPK  = "88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6"
+	aesKey   = "3F419E1CB7079442AA37474C2EFBF8B8"
+	aesBlock = "D0112P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D2" +
+		"07E2E417C07156A27E8E31DA05F7425509593D03A457DC34"
+	// Test vector published with TR-31:2018, version B.
+	tdesBKBPK  = "DD7515F2BFC17F85CE48F3CA25CB21F6"
+	tdesBKey   = "3F419E1CB7079442AA37474C2EFBF8B8"
+	tdesBBlock = "B0080P0TE00E000094B420079CC80BA3461F86FE26EFC4A3B8E4FA4C5F5341176EED7B727B8A248E"
+)
+
+func TestParseHeader(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			in     string
+			size   int
+			length int
+			blocks []tr31.Block
+			err    error
+		}{
+			{in: aesBlock, size: 16, length: 112},
+			{in: "D0144D0AB00S0200KS1800604B120F9292800000PB1800000000000000000000", size: 64, length: 144, blocks: []tr31.Block{
+				{ID: "KS", Data: "00604B120F9292800000"},
+				{ID: "PB", Data: "00000000000000000000"},
+			}},
+			{in: "D0144D0AB00S0100KS18", err: errors.Data},
+			{in: "D0144D0AB00S0100KS", err: errors.Length},
+			{in: "D01X4D0AB00S0000", err: errors.Data},
+			{in: "D0144", err: errors.Length},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			h, size, length, err := tr31.ParseHeader(tt.in)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			are.Equal(size, tt.size)
+			are.Equal(length, tt.length)
+			are.Equal(h.Version, tt.in[0])
+			are.Equal(h.Usage, tt.in[5:7])
+			are.Equal(h.Blocks, tt.blocks)
+		})
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			kbpk, in string
+			key      string
+			err      error
+		}{
+			{kbpk: aesKBPK, in: aesBlock, key: aesKey},
+			{kbpk: tdesBKBPK, in: tdesBBlock, key: tdesBKey},
+			{kbpk: tdesKBPK, in: tdesBBlock, err: errors.MAC},
+			{kbpk: aesKBPK, in: aesBlock[:len(aesBlock)-1] + "5", err: errors.MAC},
+			{kbpk: tdesKBPK[:16], in: aesBlock, err: errors.Key},
+			{kbpk: aesKBPK, in: aesBlock[:96], err: errors.Length},
+			{kbpk: tdesKBPK, in: "A0072P0TE00E0000F5161ED902807AF26F1D62263644BD24192FDB3193C730301CEE8701", err: errors.NotImplemented},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			h, key, err := tr31.Unwrap(unhex(tt.kbpk), tt.in)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			are.Equal(strings.ToUpper(hex.EncodeToString(key)), tt.key)
+			are.Equal(h.Usage, tr31.UsagePIN)
+			are.Equal(h.ModeOfUse, byte(tr31.ModeEncrypt))
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			kbpk, key string
+			h         tr31.Header
+			length    int
+		}{
+			{
+				kbpk:   tdesKBPK,
+				key:    tdesKey,
+				h:      tr31.Header{Version: tr31.VersionB, Usage: tr31.UsagePIN, Algorithm: tr31.AlgorithmTDES, ModeOfUse: tr31.ModeEncrypt, KeyVersion: "00", Exportability: tr31.Exportable},
+				length: 80,
+			},
+			{
+				kbpk: aesKBPK,
+				key:  aesKey,
+				h: tr31.Header{
+					Version: tr31.VersionD, Usage: tr31.UsageBDK, Algorithm: tr31.AlgorithmAES, ModeOfUse: tr31.ModeDerivation,
+					KeyVersion: "00", Exportability: tr31.Sensitive, Blocks: []tr31.Block{{ID: "KS", Data: "00604B120F9292800000"}},
+				},
+				length: 144,
+			},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			out, err := tr31.Wrap(unhex(tt.kbpk), tt.h, unhex(tt.key))
+			are.NoErr(err)
+			are.Equal(len(out), tt.length)
+			h, key, err := tr31.Unwrap(unhex(tt.kbpk), out)
+			are.NoErr(err)
+			are.Equal(strings.ToUpper(hex.EncodeToString(key)), tt.key)
+			are.Equal(h.Usage, tt.h.Usage)
+			ks, ok := h.Block("KS")
+			are.Equal(ok, len(tt.h.Blocks) > 0)
+			if ok {
+				are.Equal(ks, tt.h.Blocks[0].Data)
+			}
+		})
+	}
+}
+
+func TestNewField(t *testing.T) {
+	var (
+		are = is.New(t)
+		dt  = []struct {
+			spec field.Spec
+			id   field.ID
+			err  error
+		}{
+			{id: 48},
+			{spec: tr31.Spec, id: 96},
+			// The message security code is too short.
+			{id: 96, err: errors.New(errors.Length, 96)},
+		}
+	)
+	for i, tt := range dt {
+		tt := tt
+		t.Run("#"+strconv.Itoa(i), func(t *testing.T) {
+			f, err := tr31.NewField(tt.spec, tt.id, aesBlock)
+			are.Equal(err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			msg := &iso8583.Message{
+				MTI:  iso8583.NewMTI(iso8583.V1987, iso8583.NetworkManagement),
+				Spec: tt.spec,
+				Data: iso8583.Fields{tt.id: f},
+			}
+			b, err := iso8583.Marshal(msg)
+			are.NoErr(err)
+			out := &iso8583.Message{Spec: tt.spec}
+			are.NoErr(iso8583.Unmarshal(b, out))
+			s, err := tr31.FromField(out.Data[tt.id])
+			are.NoErr(err)
+			are.Equal(s, aesBlock)
+		})
+	}
+}
@@ -4,6 +4,8 @@
 
 package iso8583
 
+import "github.com/rvflash/iso8583/field"
+
 // Version represents the iso 8583 version used.
 type Version uint8
 
@@ -20,3 +22,16 @@ const (
 func (v Version) Valid() bool {
 	return v <= V2003
 }
+
+// Spec returns the built-in specification of the version.
+// An invalid version uses the one of ISO 8583:1987.
+func (v Version) Spec() field.Spec {
+	switch v {
+	case V1993:
+		return field.ISO1993
+	case V2003:
+		return field.ISO2003
+	default:
+		return field.ISO1987
+	}
+}